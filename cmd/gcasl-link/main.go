@@ -0,0 +1,112 @@
+// gcasl-link オブジェクトファイルをリンクし、実行イメージを出力する
+// .cas のファイルはアセンブルしてからリンクする
+//
+//	gcasl-link [-o a.img] [-entry MAIN] main.obj mult.obj
+//	gcasl-link -c mult.cas        (mult.obj を出力)
+//	gcasl-link -D DEBUG=1 main.cas mult.cas
+//	gcasl-link -I lib main.cas    (INCLUDE はソースと同じディレクトリ、-I のディレクトリの順に探す)
+//
+// 環境変数 GCASL_LANG=en でアセンブルエラーのメッセージを英語にする
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+	"github.com/DJSIer/OnlineGCASL2/include"
+	"github.com/DJSIer/OnlineGCASL2/object"
+	"github.com/DJSIer/OnlineGCASL2/report"
+)
+
+func main() {
+	out := flag.String("o", "a.img", "出力ファイル")
+	entry := flag.String("entry", "", "実行開始ラベル (省略時は最初のオブジェクトの START)")
+	compile := flag.Bool("c", false, ".cas をアセンブルして .obj を出力する (リンクしない)")
+	defines := flag.String("D", "", ".cas をアセンブルするときの定数 NAME=VALUE (カンマ区切り、ソースの EQU より優先)")
+	dirs := flag.String("I", "", "INCLUDE のファイルを探すディレクトリ (カンマ区切り)")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	var objs []*object.Object
+	for _, name := range flag.Args() {
+		o, err := load(name, *defines, *dirs)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if *compile {
+			if err := write(strings.TrimSuffix(name, filepath.Ext(name))+".obj", o.Write); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			continue
+		}
+		objs = append(objs, o)
+	}
+	if *compile {
+		return
+	}
+	img, err := object.Link(objs, *entry)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := write(*out, img.Write); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// load .cas はアセンブル、それ以外はオブジェクトファイルとして読み込む
+func load(name, defines, dirs string) (*object.Object, error) {
+	if filepath.Ext(name) != ".cas" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		o, err := object.Read(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %v", name, err)
+		}
+		return o, nil
+	}
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	inc := append(include.Path{filepath.Dir(name)}, include.ParsePath(dirs)...)
+	o, p, err := object.Assemble(filepath.Base(name), string(src), defines, inc.Source)
+	r := report.New(os.Stderr, name, parser.MatchLanguage(os.Getenv("GCASL_LANG")))
+	if err != nil {
+		var b strings.Builder
+		r.W = &b
+		r.Errors(p.Errors())
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "%s: error: %v\n", name, err)
+		}
+		return nil, fmt.Errorf("%s", strings.TrimRight(b.String(), "\n"))
+	}
+	r.Warnings(p.Warnings())
+	return o, nil
+}
+
+func write(name string, w func(f io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := w(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
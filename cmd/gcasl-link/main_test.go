@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DJSIer/OnlineGCASL2/object"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	os.Mkdir(lib, 0755)
+	files := map[string]string{
+		"mult.cas":    "MULT\tSTART\n\tLAD\tGR0,0\nLOOP\tADDA\tGR0,GR1\n\tSUBA\tGR2,=1\n\tJNZ\tLOOP\n\tRET\n\tEND\n",
+		"if.cas":      "MAIN\tSTART\n\tIF\tDEBUG\n\tLAD\tGR1,1\n\tENDIF\n\tRET\n\tEND\n",
+		"inc.cas":     "MAIN\tSTART\n\tINCLUDE\t'set.cas'\n\tRET\n\tEND\n",
+		"lib/set.cas": "\tLAD\tGR1,1\n",
+		"bad.cas":     "MAIN\tSTART\n\tLD\tGR9,X\n\tRET\nX\tDS\t1\n\tEND\n",
+		"broken.obj":  "{",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		defines string
+		dirs    string
+		words   int
+		err     string
+	}{
+		{"mult.cas", "", "", 11, ""},
+		{"if.cas", "DEBUG=1", "", 5, ""},
+		{"if.cas", "DEBUG=0", "", 3, ""},
+		{"inc.cas", "", lib, 5, ""},
+		{"inc.cas", "", "", 0, `"set.cas"`},
+		{"bad.cas", "", "", 0, "bad.cas:2:5: error[E0103]"},
+		{"none.cas", "", "", 0, "none.cas"},
+		{"broken.obj", "", "", 0, "broken.obj : "},
+	}
+	for _, tt := range tests {
+		o, err := load(filepath.Join(dir, tt.name), tt.defines, tt.dirs)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s : %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || len(o.Code) != tt.words {
+			t.Errorf("%s %s : %v %v, want %d words", tt.name, tt.defines, o, err, tt.words)
+		}
+	}
+}
+
+// TestWrite -c で出力したオブジェクトを読み込める
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "mult.cas")
+	ioutil.WriteFile(src, []byte("MULT\tSTART\n\tRET\n\tEND\n"), 0644)
+	o, err := load(src, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := filepath.Join(dir, "mult.obj")
+	if err := write(obj, o.Write); err != nil {
+		t.Fatal(err)
+	}
+	read, err := load(obj, "", "")
+	if err != nil || len(read.Code) != len(o.Code) {
+		t.Fatalf("%v %v", read, err)
+	}
+	if _, err := object.Link([]*object.Object{read}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := write(filepath.Join(dir, "none", "a.img"), o.Write); err == nil {
+		t.Error("存在しないディレクトリに書き込めます")
+	}
+}
@@ -0,0 +1,18 @@
+// gcasl-lsp CASL2 の Language Server。標準入出力で JSON-RPC を受け付ける
+//
+// diagnostics・定義へ移動・参照の検索・hover・補完・document symbol に対応する
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DJSIer/OnlineGCASL2/lsp"
+)
+
+func main() {
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, "gcasl-lsp:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,55 @@
+// gcasl-xref CASL2 ソースのラベルの相互参照表 (定義行・番地・参照行) を出力する
+// 参照されていないラベルは警告として標準エラー出力に出す
+//
+//	gcasl-xref [-json] main.cas
+//
+// INCLUDE のファイルはソースと同じディレクトリから探す
+// 環境変数 GCASL_LANG=en でエラー・警告のメッセージを英語にする
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+	"github.com/DJSIer/OnlineGCASL2/include"
+	"github.com/DJSIer/OnlineGCASL2/report"
+	"github.com/DJSIer/OnlineGCASL2/xref"
+)
+
+func main() {
+	asJSON := flag.Bool("json", false, "JSON で出力する")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	name := flag.Arg(0)
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	p := parser.New(lexer.New(string(src)))
+	p.SetIncluder(include.Path{filepath.Dir(name)}.Source)
+	code, err := p.Assemble()
+	r := report.New(os.Stderr, name, parser.MatchLanguage(os.Getenv("GCASL_LANG")))
+	if err != nil {
+		r.Errors(p.Errors())
+		os.Exit(1)
+	}
+	r.Warnings(p.Warnings())
+	entries := xref.New(code, p.SymbolTable())
+	if *asJSON {
+		b, _ := json.MarshalIndent(entries, "", "  ")
+		fmt.Println(string(b))
+	} else {
+		fmt.Print(xref.Text(entries))
+	}
+	r.Warnings(xref.Warnings(entries))
+}
@@ -0,0 +1,326 @@
+// gcasl CASL2 のアセンブル・実行を行うコマンド
+//
+//	gcasl asm [-f obj|listing|json|xref] [-o out] [-D NAME=VALUE,...] [-I dir,...] main.cas
+//	gcasl run [-limit 1000000] [-trace jsonl|csv] [-D NAME=VALUE,...] [-I dir,...] main.cas [mult.cas mult.obj a.img ...]
+//
+// -D の定数はソースの同名の EQU より優先する (IF で切り替える版の指定に使う)
+// INCLUDE 'file' はソースと同じディレクトリ、-I のディレクトリの順に探す (ディレクトリの外は参照できない)
+// run は IN を標準入力、OUT を標準出力につなぐ。複数のファイルはリンクしてから実行する
+// エラーは file:line: message の形式で標準エラー出力に出す (INCLUDE したファイルの中なら INCLUDE の位置を続ける)
+// 環境変数 GCASL_LANG=en でエラーメッセージを英語にする
+//
+// 終了コード 0:正常 1:アセンブル・リンクエラー 2:引数の誤り 3:実行時エラー
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/comet2"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+	"github.com/DJSIer/OnlineGCASL2/include"
+	"github.com/DJSIer/OnlineGCASL2/listing"
+	"github.com/DJSIer/OnlineGCASL2/object"
+	"github.com/DJSIer/OnlineGCASL2/report"
+	"github.com/DJSIer/OnlineGCASL2/xref"
+)
+
+// exit status
+const (
+	exitOK      = 0
+	exitAsm     = 1
+	exitUsage   = 2
+	exitRuntime = 3
+)
+
+// lang エラーメッセージの言語 (GCASL_LANG)
+var lang = parser.MatchLanguage(os.Getenv("GCASL_LANG"))
+
+const usage = `usage:
+  gcasl asm [-f obj|listing|json|xref] [-o out] [-D NAME=VALUE,...] [-I dir,...] file.cas
+  gcasl run [-limit n] [-trace jsonl|csv] [-D NAME=VALUE,...] [-I dir,...] file.cas [file.cas|file.obj|file.img ...]
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	}
+	switch os.Args[1] {
+	case "asm":
+		os.Exit(asm(os.Args[2:]))
+	case "run":
+		os.Exit(run(os.Args[2:]))
+	case "-h", "-help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "gcasl: %q : 不明なコマンドです。\n", os.Args[1])
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	}
+}
+
+// source アセンブル結果とソース
+type source struct {
+	name string
+	lex  *lexer.Lexer
+	p    *parser.Parser
+	code []opcode.Opcode
+}
+
+// assemble name を読み込んでアセンブルする。エラーは file:line: message で出力する
+func assemble(name, defines, dirs string) (*source, bool) {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+	s := &source{name: name, lex: lexer.New(string(src))}
+	s.p = parser.New(s.lex)
+	s.p.SetIncluder(includer(name, dirs))
+	s.p.DefineConstants(defines)
+	s.code, err = s.p.Assemble()
+	if err != nil {
+		reporter(name).Errors(s.p.Errors())
+		if len(s.p.Errors()) == 0 {
+			fmt.Fprintf(os.Stderr, "%s: error: %v\n", name, err)
+		}
+		return nil, false
+	}
+	return s, true
+}
+
+// assembleObject name をオブジェクトにアセンブルする (他のファイルのラベルは imports になる)
+func assembleObject(name, defines, dirs string) (*object.Object, bool) {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+	o, p, err := object.Assemble(filepath.Base(name), string(src), defines, includer(name, dirs))
+	if err != nil {
+		reporter(name).Errors(p.Errors())
+		if len(p.Errors()) == 0 {
+			fmt.Fprintf(os.Stderr, "%s: error: %v\n", name, err)
+		}
+		return nil, false
+	}
+	reporter(name).Warnings(p.Warnings())
+	return o, true
+}
+
+// includer name と同じディレクトリ、dirs (カンマ区切り) の順に INCLUDE のファイルを探す
+func includer(name, dirs string) parser.Includer {
+	return append(include.Path{filepath.Dir(name)}, include.ParsePath(dirs)...).Source
+}
+
+// reporter name のエラー・警告を標準エラー出力に出す
+func reporter(name string) *report.Reporter {
+	return report.New(os.Stderr, name, lang)
+}
+
+func asm(args []string) int {
+	fs := flag.NewFlagSet("asm", flag.ContinueOnError)
+	format := fs.String("f", "obj", "出力形式 obj・listing・json・xref")
+	out := fs.String("o", "", "出力ファイル (省略時は obj は file.obj、それ以外は標準出力)")
+	defines := fs.String("D", "", "定数 NAME=VALUE (カンマ区切り)")
+	dirs := fs.String("I", "", "INCLUDE のファイルを探すディレクトリ (カンマ区切り、ソースのディレクトリの後に探す)")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitUsage
+	}
+	name := fs.Arg(0)
+	var write func(w io.Writer) error
+	switch *format {
+	case "obj":
+		o, ok := assembleObject(name, *defines, *dirs)
+		if !ok {
+			return exitAsm
+		}
+		if *out == "" {
+			*out = strings.TrimSuffix(name, filepath.Ext(name)) + ".obj"
+		}
+		write = o.Write
+	case "listing", "json", "xref":
+		s, ok := assemble(name, *defines, *dirs)
+		if !ok {
+			return exitAsm
+		}
+		reporter(name).Warnings(s.p.Warnings())
+		switch *format {
+		case "listing":
+			write = text(listing.Text(listing.New(s.code, s.lex, s.p.SymbolTable())))
+		case "xref":
+			entries := xref.New(s.code, s.p.SymbolTable())
+			reporter(name).Warnings(xref.Warnings(entries))
+			write = text(xref.Text(entries))
+		default:
+			entry, _ := s.p.Entry()
+			write = func(w io.Writer) error {
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				return enc.Encode(map[string]interface{}{
+					"code":    s.code,
+					"entry":   entry,
+					"warning": parser.LocalizeWarnings(s.p.Warnings(), lang),
+				})
+			}
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "gcasl: %q : 出力形式は obj・listing・json・xref のいずれかです。\n", *format)
+		return exitUsage
+	}
+	if err := output(*out, write); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitAsm
+	}
+	return exitOK
+}
+
+func text(s string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+// output name が空なら標準出力に書き出す
+func output(name string, write func(w io.Writer) error) error {
+	if name == "" || name == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func run(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	limit := fs.Int("limit", 1000000, "実行命令数の上限 (0 は無制限)")
+	trace := fs.String("trace", "", "実行トレースを標準エラー出力に出す (jsonl・csv)")
+	defines := fs.String("D", "", "定数 NAME=VALUE (カンマ区切り)")
+	dirs := fs.String("I", "", "INCLUDE のファイルを探すディレクトリ (カンマ区切り、ソースのディレクトリの後に探す)")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitUsage
+	}
+	m := comet2.New()
+	var name string //実行時エラーの表示に使うソース (1ファイルの場合のみ行番号が分かる)
+	if fs.NArg() == 1 && filepath.Ext(fs.Arg(0)) == ".cas" {
+		name = fs.Arg(0)
+		s, ok := assemble(name, *defines, *dirs)
+		if !ok {
+			return exitAsm
+		}
+		reporter(name).Warnings(s.p.Warnings())
+		if err := m.Load(s.code); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: error: %v\n", name, err)
+			return exitAsm
+		}
+	} else {
+		img, ok := link(fs.Args(), *defines, *dirs)
+		if !ok {
+			return exitAsm
+		}
+		if err := m.LoadImage(img.Code, img.Entry); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitAsm
+		}
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	m.Stdin = os.Stdin
+	m.Stdout = out
+	var output strings.Builder //CSV の最終行の OUT の出力
+	switch *trace {
+	case "":
+	case "jsonl":
+		m.Tracer = comet2.NewJSONLTracer(os.Stderr)
+	case "csv":
+		m.Tracer = comet2.NewCSVTracer(os.Stderr)
+		m.Stdout = io.MultiWriter(out, &output)
+	default:
+		fmt.Fprintf(os.Stderr, "gcasl: %q : trace は jsonl・csv のいずれかです。\n", *trace)
+		return exitUsage
+	}
+	err := m.Run(*limit)
+	if *trace == "csv" {
+		comet2.WriteCSVResult(m.Tracer, m, output.String(), err)
+	}
+	if err != nil {
+		out.Flush()
+		if line := m.Line(); name != "" && line > 0 {
+			fmt.Fprintf(os.Stderr, "%s:%d: runtime error: %v\n", name, line, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "runtime error: PR #%04X: %v\n", m.PR, err)
+		}
+		return exitRuntime
+	}
+	return exitOK
+}
+
+// link .cas・.obj をリンクする。.img はそのまま読み込む
+func link(names []string, defines, dirs string) (*object.Image, bool) {
+	var objs []*object.Object
+	for _, name := range names {
+		switch filepath.Ext(name) {
+		case ".cas":
+			o, ok := assembleObject(name, defines, dirs)
+			if !ok {
+				return nil, false
+			}
+			objs = append(objs, o)
+		case ".img":
+			if len(names) != 1 {
+				fmt.Fprintf(os.Stderr, "%s: error: 実行イメージは他のファイルとリンクできません。\n", name)
+				return nil, false
+			}
+			f, err := os.Open(name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return nil, false
+			}
+			img, err := object.ReadImage(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: error: %v\n", name, err)
+				return nil, false
+			}
+			return img, true
+		default:
+			f, err := os.Open(name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return nil, false
+			}
+			o, err := object.Read(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: error: %v\n", name, err)
+				return nil, false
+			}
+			objs = append(objs, o)
+		}
+	}
+	img, err := object.Link(objs, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "link error: %v\n", err)
+		return nil, false
+	}
+	return img, true
+}
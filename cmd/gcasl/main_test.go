@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// files テストで使うソース (INCLUDE・リンクの確認用)
+var files = map[string]string{
+	"echo.cas": "MAIN\tSTART\n\tIN\tBUF,LEN\n\tOUT\tBUF,LEN\n\tRET\nBUF\tDS\t256\nLEN\tDS\t1\n\tEND\n",
+	"main.cas": "MAIN\tSTART\n\tLAD\tGR1,3\n\tLAD\tGR2,4\n\tCALL\tMULT\n\tST\tGR0,ANS\n\tOUT\tMSG,LEN\n\tRET\nANS\tDS\t1\nMSG\tDC\t'MULT'\nLEN\tDC\t4\n\tEND\n",
+	"mult.cas": "MULT\tSTART\n\tLAD\tGR0,0\nLOOP\tADDA\tGR0,GR1\n\tSUBA\tGR2,=1\n\tJNZ\tLOOP\n\tRET\n\tEND\n",
+	"inc.cas":  "MAIN\tSTART\n\tINCLUDE\t'lib.cas'\n\tRET\n\tEND\n",
+	"lib.cas":  "\tLD\tGR9,X\nX\tDS\t1\n",
+	"bad.cas":  "MAIN\tSTART\n\tLD\tGR9,X\n\tFOO\tGR1\n\tRET\nX\tDS\t1\n\tEND\n",
+	"loop.cas": "MAIN\tSTART\nL\tJUMP\tL\n\tEND\n",
+	"warn.cas": "MAIN\tSTART\n\tLD\tGR0,X\n\tRET\nX\tDS\t1\nY\tDS\t1\n\tEND\n",
+	"if.cas":   "MAIN\tSTART\n\tIF\tDEBUG\n\tOUT\tMSG,LEN\n\tENDIF\n\tRET\nMSG\tDC\t'DEBUG'\nLEN\tDC\t5\n\tEND\n",
+}
+
+// capture 標準入出力を一時ファイルにして f を実行する
+func capture(t *testing.T, stdin string, f func() int) (int, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	open := func(name, content string) *os.File {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte(content), 0644)
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	in, out, errOut := open("stdin", stdin), open("stdout", ""), open("stderr", "")
+	defer in.Close()
+	defer out.Close()
+	defer errOut.Close()
+	saved := [3]*os.File{os.Stdin, os.Stdout, os.Stderr}
+	os.Stdin, os.Stdout, os.Stderr = in, out, errOut
+	code := f()
+	os.Stdin, os.Stdout, os.Stderr = saved[0], saved[1], saved[2]
+	o, _ := ioutil.ReadFile(out.Name())
+	e, _ := ioutil.ReadFile(errOut.Name())
+	return code, string(o), string(e)
+}
+
+// workdir files を書き出したディレクトリ
+func workdir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := workdir(t)
+	path := func(name string) string { return filepath.Join(dir, name) }
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"echo", []string{path("echo.cas")}, "hello\n", exitOK, "hello\n", ""},
+		{"link", []string{path("main.cas"), path("mult.cas")}, "", exitOK, "MULT\n", ""},
+		{"define", []string{"-D", "DEBUG=1", path("if.cas")}, "", exitOK, "DEBUG\n", ""},
+		{"no define", []string{path("if.cas")}, "", exitAsm, "", "if.cas:2:5: error[E0209]"},
+		{"errors", []string{path("bad.cas")}, "", exitAsm, "", "bad.cas:2:5: error[E0103]"},
+		{"include", []string{path("inc.cas")}, "", exitAsm, "", "lib.cas:1:5: error[E0103]"},
+		{"unresolved", []string{path("main.cas")}, "", exitAsm, "", `main.cas:4:7: error[E0002]: "MULT"は解決できません`},
+		{"limit", []string{"-limit", "100", path("loop.cas")}, "", exitRuntime, "", "loop.cas:2: runtime error"},
+		{"trace", []string{"-trace", "xml", path("echo.cas")}, "", exitUsage, "", "trace"},
+		{"usage", nil, "", exitUsage, "", "usage"},
+		{"missing", []string{path("none.cas")}, "", exitAsm, "", "none.cas"},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := capture(t, tt.stdin, func() int { return run(tt.args) })
+		if code != tt.code || stdout != tt.stdout || !strings.Contains(stderr, tt.stderr) {
+			t.Errorf("%s : %d %q %q, want %d %q %q", tt.name, code, stdout, stderr, tt.code, tt.stdout, tt.stderr)
+		}
+	}
+}
+
+func TestAsm(t *testing.T) {
+	dir := workdir(t)
+	path := func(name string) string { return filepath.Join(dir, name) }
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"listing", []string{"-f", "listing", path("mult.cas")}, exitOK, "   3 0003 2401      LOOP\tADDA\tGR0,GR1\n", ""},
+		{"xref", []string{"-f", "xref", path("warn.cas")}, exitOK, "Y     0005    5\n", "warn.cas:5:1: warning[W0002]"},
+		{"json", []string{"-f", "json", path("mult.cas")}, exitOK, `"entry": 0`, ""},
+		{"format", []string{"-f", "hex", path("mult.cas")}, exitUsage, "", "出力形式"},
+		{"errors", []string{"-f", "listing", path("bad.cas")}, exitAsm, "", "bad.cas:3:6: error[E0003]"},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := capture(t, "", func() int { return asm(tt.args) })
+		if code != tt.code || !strings.Contains(stdout, tt.stdout) || !strings.Contains(stderr, tt.stderr) {
+			t.Errorf("%s : %d %q %q, want %d %q %q", tt.name, code, stdout, stderr, tt.code, tt.stdout, tt.stderr)
+		}
+	}
+}
+
+// TestObject asm で書き出したオブジェクトをリンクして実行する
+func TestObject(t *testing.T) {
+	dir := workdir(t)
+	for _, name := range []string{"main", "mult"} {
+		if code, _, stderr := capture(t, "", func() int { return asm([]string{filepath.Join(dir, name+".cas")}) }); code != exitOK {
+			t.Fatalf("%s : %d %s", name, code, stderr)
+		}
+	}
+	code, stdout, stderr := capture(t, "", func() int {
+		return run([]string{filepath.Join(dir, "main.obj"), filepath.Join(dir, "mult.obj")})
+	})
+	if code != exitOK || stdout != "MULT\n" {
+		t.Fatalf("%d %q %q", code, stdout, stderr)
+	}
+}
+
+// TestTraceCSV -trace csv の最終行は実行結果
+func TestTraceCSV(t *testing.T) {
+	dir := workdir(t)
+	code, stdout, stderr := capture(t, "hi\n", func() int { return run([]string{"-trace", "csv", filepath.Join(dir, "echo.cas")}) })
+	records, err := csv.NewReader(strings.NewReader(stderr)).ReadAll()
+	if code != exitOK || stdout != "hi\n" || err != nil || len(records) < 3 {
+		t.Fatalf("%d %q %q %v", code, stdout, stderr, err)
+	}
+	if last := records[len(records)-1]; last[2] != "OK" || last[9] != "hi\n" || last[10] != "" {
+		t.Errorf("%q", last)
+	}
+	code, _, stderr = capture(t, "", func() int { return run([]string{"-trace", "csv", "-limit", "3", filepath.Join(dir, "loop.cas")}) })
+	if lines := strings.Split(stderr, "\n"); code != exitRuntime || len(lines) < 3 || !strings.HasPrefix(lines[len(lines)-3], "3,#0001,NG,") {
+		t.Errorf("%d %q", code, stderr)
+	}
+}
@@ -0,0 +1,122 @@
+package comet2
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Access watchpoint で監視するアクセス
+type Access int
+
+// Access kind
+const (
+	AccessRead Access = 1 << iota
+	AccessWrite
+	AccessReadWrite = AccessRead | AccessWrite
+)
+
+// Watchpoint Start~End (End を含む) の語へのアクセスで停止する
+type Watchpoint struct {
+	Start  uint16 `json:"start"`
+	End    uint16 `json:"end"`
+	Access Access `json:"access"`
+}
+
+// Stop 実行が停止した理由
+type Stop struct {
+	Reason string `json:"reason"`          //breakpoint, watchpoint
+	Addr   uint16 `json:"addr"`            //停止した番地・アクセスされた番地
+	Write  bool   `json:"write,omitempty"` //watchpoint : 書き込みによる停止
+	Value  uint16 `json:"value,omitempty"` //watchpoint : 読み書きされた値
+}
+
+// SetBreakpoint 番地にブレークポイントを設定する
+func (m *Machine) SetBreakpoint(addr uint16) {
+	if m.breakpoints == nil {
+		m.breakpoints = map[uint16]bool{}
+	}
+	m.breakpoints[addr] = true
+}
+
+// SetBreakpointLine ソース行の命令にブレークポイントを設定する
+func (m *Machine) SetBreakpointLine(line int) (uint16, error) {
+	addr, ok := m.LineAddress(line)
+	if !ok {
+		return 0, fmt.Errorf("%d行目に命令がありません。", line)
+	}
+	m.SetBreakpoint(addr)
+	return addr, nil
+}
+
+// ClearBreakpoint ブレークポイントを解除する
+func (m *Machine) ClearBreakpoint(addr uint16) {
+	delete(m.breakpoints, addr)
+}
+
+// Breakpoints 設定中のブレークポイント
+func (m *Machine) Breakpoints() []uint16 {
+	addrs := make([]uint16, 0, len(m.breakpoints))
+	for addr := range m.breakpoints {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	return addrs
+}
+
+// AddWatchpoint watchpoint を追加する
+func (m *Machine) AddWatchpoint(w Watchpoint) error {
+	if w.End < w.Start {
+		return fmt.Errorf("watchpoint の範囲が不正です。#%04X~#%04X", w.Start, w.End)
+	}
+	if w.Access&AccessReadWrite == 0 {
+		w.Access = AccessReadWrite
+	}
+	m.watchpoints = append(m.watchpoints, w)
+	return nil
+}
+
+// ClearWatchpoints watchpoint をすべて解除する
+func (m *Machine) ClearWatchpoints() {
+	m.watchpoints = nil
+}
+
+// Watchpoints 設定中の watchpoint
+func (m *Machine) Watchpoints() []Watchpoint {
+	return m.watchpoints
+}
+
+// Stopped 直前の Step・RunUntil がブレークポイント・watchpoint で停止した理由 (それ以外は nil)
+func (m *Machine) Stopped() *Stop {
+	return m.stop
+}
+
+// read プログラムによるメモリ読み出し
+func (m *Machine) read(addr uint16) uint16 {
+	v := m.Memory[addr]
+	m.watch(addr, v, AccessRead)
+	return v
+}
+
+// write プログラムによるメモリ書き込み
+func (m *Machine) write(addr uint16, v uint16) {
+	if m.undo != nil {
+		m.undo.Memory = append(m.undo.Memory, MemoryWrite{Addr: addr, Old: m.Memory[addr]})
+	}
+	m.Memory[addr] = v
+	if m.trace != nil {
+		m.trace.Memory = append(m.trace.Memory, MemoryValue{Addr: addr, Value: v})
+	}
+	m.watch(addr, v, AccessWrite)
+}
+
+func (m *Machine) watch(addr uint16, v uint16, access Access) {
+	if m.stop != nil {
+		return
+	}
+	for _, w := range m.watchpoints {
+		if w.Access&access != 0 && w.Start <= addr && addr <= w.End {
+			m.stop = &Stop{Reason: "watchpoint", Addr: addr, Write: access == AccessWrite, Value: v}
+			return
+		}
+	}
+}
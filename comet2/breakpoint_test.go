@@ -0,0 +1,105 @@
+package comet2
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 0:START 1:LAD 3:ST 5:LD 7:CALL 9:RET 10:SUB 12:RET 13:X 14:Y
+const watched = `MAIN	START
+	LAD	GR1,1
+	ST	GR1,X
+	LD	GR2,Y
+	CALL	SUB
+	RET
+SUB	LAD	GR3,3
+	RET
+X	DS	1
+Y	DC	5
+	END
+`
+
+func TestStopped(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(m *Machine)
+		stops []Stop
+	}{
+		{"none", func(m *Machine) {}, nil},
+		{"breakpoint", func(m *Machine) { m.SetBreakpoint(10) }, []Stop{{Reason: "breakpoint", Addr: 10}}},
+		{"breakpoint line", func(m *Machine) { m.SetBreakpointLine(4) }, []Stop{{Reason: "breakpoint", Addr: 5}}},
+		{"clear", func(m *Machine) { m.SetBreakpoint(10); m.ClearBreakpoint(10) }, nil},
+		{"first instruction", func(m *Machine) { m.SetBreakpoint(0) }, nil},
+		{"write", func(m *Machine) { m.AddWatchpoint(Watchpoint{Start: 13, End: 13, Access: AccessWrite}) },
+			[]Stop{{Reason: "watchpoint", Addr: 13, Write: true, Value: 1}}},
+		{"read", func(m *Machine) { m.AddWatchpoint(Watchpoint{Start: 13, End: 14, Access: AccessRead}) },
+			[]Stop{{Reason: "watchpoint", Addr: 14, Value: 5}}},
+		{"readwrite", func(m *Machine) { m.AddWatchpoint(Watchpoint{Start: 13, End: 14}) },
+			[]Stop{{Reason: "watchpoint", Addr: 13, Write: true, Value: 1}, {Reason: "watchpoint", Addr: 14, Value: 5}}},
+		// CALL はスタックに書き込む
+		{"stack", func(m *Machine) { m.AddWatchpoint(Watchpoint{Start: 0xFFFF, End: 0xFFFF, Access: AccessWrite}) },
+			[]Stop{{Reason: "watchpoint", Addr: 0xFFFF, Write: true, Value: 9}}},
+		{"clear watchpoints", func(m *Machine) { m.AddWatchpoint(Watchpoint{Start: 13, End: 14}); m.ClearWatchpoints() }, nil},
+		// watchpoint で停止した位置のブレークポイントでは再開時に止まらない
+		{"both", func(m *Machine) {
+			m.SetBreakpoint(5)
+			m.SetBreakpoint(12)
+			m.AddWatchpoint(Watchpoint{Start: 13, End: 13, Access: AccessWrite})
+		}, []Stop{{Reason: "watchpoint", Addr: 13, Write: true, Value: 1}, {Reason: "breakpoint", Addr: 12}}},
+	}
+	for _, tt := range tests {
+		m := assemble(t, watched)
+		tt.setup(m)
+		var stops []Stop
+		for !m.Halted() {
+			if err := m.Run(100); err != nil {
+				t.Fatalf("%s : %v", tt.name, err)
+			}
+			if s := m.Stopped(); s != nil {
+				stops = append(stops, *s)
+			}
+		}
+		if !reflect.DeepEqual(stops, tt.stops) {
+			t.Errorf("%s : stops %+v, want %+v", tt.name, stops, tt.stops)
+		}
+		if m.GR[3] != 3 {
+			t.Errorf("%s : 最後まで実行されていません", tt.name)
+		}
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	m := assemble(t, watched)
+	for _, addr := range []uint16{10, 1, 5, 10} {
+		m.SetBreakpoint(addr)
+	}
+	if got := m.Breakpoints(); !reflect.DeepEqual(got, []uint16{1, 5, 10}) {
+		t.Fatalf("breakpoints %v", got)
+	}
+	if addr, err := m.SetBreakpointLine(7); err != nil || addr != 10 {
+		t.Fatalf("SUB の行 #%04X %v", addr, err)
+	}
+	if _, err := m.SetBreakpointLine(20); err == nil {
+		t.Fatal("命令のない行に設定できます")
+	}
+	if err := m.AddWatchpoint(Watchpoint{Start: 14, End: 13}); err == nil {
+		t.Fatal("範囲が逆の watchpoint を追加できます")
+	}
+}
+
+func TestStepStopped(t *testing.T) {
+	m := assemble(t, watched)
+	m.AddWatchpoint(Watchpoint{Start: 13, End: 13, Access: AccessWrite})
+	for m.PR != 5 {
+		if err := m.Step(); err != nil {
+			t.Fatal(err)
+		}
+		if s := m.Stopped(); (s != nil) != (m.PR == 5) {
+			t.Fatalf("PR #%04X stop %+v", m.PR, s)
+		}
+	}
+	m.Step()
+	if m.Stopped() != nil {
+		t.Fatal("次の Step で停止理由が残っています")
+	}
+}
@@ -0,0 +1,38 @@
+package comet2
+
+import (
+	"fmt"
+)
+
+// Line PR が指す命令のソース行番号 (不明な場合は 0)
+func (m *Machine) Line() int {
+	return m.lines[m.PR]
+}
+
+// LineAddress ソース行番号に対応する命令の先頭アドレス
+func (m *Machine) LineAddress(line int) (uint16, bool) {
+	addr, ok := m.lineAddr[line]
+	return addr, ok
+}
+
+// StepOver 1命令実行する。CALL の場合はサブルーチンから戻るまで実行する
+func (m *Machine) StepOver(limit int) error {
+	if m.Memory[m.PR]>>8 != 0x80 {
+		return m.Step()
+	}
+	ret, sp := m.PR+2, m.SP
+	return m.RunUntil(limit, func(m *Machine) bool {
+		return m.PR == ret && m.SP == sp
+	})
+}
+
+// RunToLine ソース行の命令に到達するまで実行する
+func (m *Machine) RunToLine(limit int, line int) error {
+	addr, ok := m.LineAddress(line)
+	if !ok {
+		return fmt.Errorf("%d行目に命令がありません。", line)
+	}
+	return m.RunUntil(limit, func(m *Machine) bool {
+		return m.PR == addr
+	})
+}
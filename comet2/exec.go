@@ -0,0 +1,172 @@
+package comet2
+
+import (
+	"fmt"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+)
+
+// Step 1命令実行
+func (m *Machine) Step() error {
+	if m.halted {
+		return fmt.Errorf("プログラムは終了しています。")
+	}
+	m.stop = nil
+	m.record()
+	var err error
+	if m.Tracer != nil {
+		err = m.traceStep()
+	} else {
+		err = m.step()
+	}
+	if err != nil {
+		// 実行できなかった命令は履歴に残さない (m.Steps も進んでいない)
+		m.undo = nil
+		return err
+	}
+	m.commit()
+	return nil
+}
+
+func (m *Machine) step() error {
+	word := m.Memory[m.PR]
+	op := uint8(word >> 8)
+	r := (word >> 4) & 0x0F
+	x := word & 0x0F
+	if r > 7 || x > 7 {
+		return fmt.Errorf("不正な命令です。PR : #%04X 命令 : #%04X", m.PR, word)
+	}
+	next := m.PR + 1
+	var adr, v uint16
+	if inst, _ := opcode.Lookup(op); inst.Length() == 2 {
+		adr = m.effectiveAddress(m.Memory[m.PR+1], x)
+		next = m.PR + 2
+		if m.trace != nil {
+			m.trace.Addr = &adr
+		}
+		if inst.Has(opcode.ReadsMemory) {
+			v = m.read(adr)
+		}
+	} else {
+		// r1,r2 形式では x が r2
+		v = m.GR[x]
+	}
+
+	switch op {
+	case 0x00: // NOP
+	case 0x10, 0x14: // LD
+		m.GR[r] = v
+		m.setFlags(v, false)
+	case 0x11: // ST
+		m.write(adr, m.GR[r])
+	case 0x12: // LAD
+		m.GR[r] = adr
+	case 0x20, 0x24: // ADDA
+		a := int32(int16(m.GR[r])) + int32(int16(v))
+		m.GR[r] = uint16(a)
+		m.setFlags(m.GR[r], a > 32767 || a < -32768)
+	case 0x21, 0x25: // SUBA
+		a := int32(int16(m.GR[r])) - int32(int16(v))
+		m.GR[r] = uint16(a)
+		m.setFlags(m.GR[r], a > 32767 || a < -32768)
+	case 0x22, 0x26: // ADDL
+		a := uint32(m.GR[r]) + uint32(v)
+		m.GR[r] = uint16(a)
+		m.setFlags(m.GR[r], a > 0xFFFF)
+	case 0x23, 0x27: // SUBL
+		borrow := m.GR[r] < v
+		m.GR[r] -= v
+		m.setFlags(m.GR[r], borrow)
+	case 0x30, 0x34: // AND
+		m.GR[r] &= v
+		m.setFlags(m.GR[r], false)
+	case 0x31, 0x35: // OR
+		m.GR[r] |= v
+		m.setFlags(m.GR[r], false)
+	case 0x32, 0x36: // XOR
+		m.GR[r] ^= v
+		m.setFlags(m.GR[r], false)
+	case 0x40, 0x44: // CPA
+		a, b := int16(m.GR[r]), int16(v)
+		m.FR = Flags{SF: a < b, ZF: a == b}
+	case 0x41, 0x45: // CPL
+		a, b := m.GR[r], v
+		m.FR = Flags{SF: a < b, ZF: a == b}
+	case 0x50, 0x51, 0x52, 0x53: // SLA SRA SLL SRL
+		res, of := shift(op, m.GR[r], adr)
+		m.GR[r] = res
+		m.setFlags(res, of)
+	case 0x61: // JMI
+		if m.FR.SF {
+			next = adr
+		}
+	case 0x62: // JNZ
+		if !m.FR.ZF {
+			next = adr
+		}
+	case 0x63: // JZE
+		if m.FR.ZF {
+			next = adr
+		}
+	case 0x64: // JUMP
+		next = adr
+	case 0x65: // JPL
+		if !m.FR.SF && !m.FR.ZF {
+			next = adr
+		}
+	case 0x66: // JOV
+		if m.FR.OF {
+			next = adr
+		}
+	case 0x70: // PUSH
+		m.push(adr)
+	case 0x71: // POP
+		m.GR[r] = m.pop()
+	case 0x80: // CALL
+		m.push(next)
+		next = adr
+	case 0x81: // RET
+		if m.SP == 0 {
+			// スタックが空の RET はOSへの復帰
+			m.halted = true
+			m.Steps++
+			return nil
+		}
+		next = m.pop()
+	case 0xF0: // SVC
+		if err := m.svc(adr); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("不正な命令です。PR : #%04X 命令 : #%04X", m.PR, word)
+	}
+	m.PR = next
+	m.Steps++
+	return nil
+}
+
+// shift SLA・SRA・SLL・SRL 最後に送り出されたビットを OF とする
+func shift(op uint8, v uint16, n uint16) (uint16, bool) {
+	of := false
+	if n > 17 {
+		n = 17
+	}
+	sign := v & 0x8000
+	for i := uint16(0); i < n; i++ {
+		switch op {
+		case 0x50: // SLA
+			of = v&0x4000 != 0
+			v = sign | (v<<1)&0x7FFF
+		case 0x51: // SRA
+			of = v&0x0001 != 0
+			v = sign | v>>1
+		case 0x52: // SLL
+			of = v&0x8000 != 0
+			v <<= 1
+		case 0x53: // SRL
+			of = v&0x0001 != 0
+			v >>= 1
+		}
+	}
+	return v, of
+}
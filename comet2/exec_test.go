@@ -0,0 +1,115 @@
+package comet2
+
+import (
+	"testing"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+)
+
+// assemble src をアセンブルして Machine に配置する
+func assemble(t *testing.T, src string) *Machine {
+	t.Helper()
+	p := parser.New(lexer.New(src))
+	code, err := p.Assemble()
+	if err != nil {
+		t.Fatalf("%v %v", err, p.Errors())
+	}
+	m := New()
+	if err := m.Load(code); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// program 命令の行を START・RET・END で囲む
+func program(body string) string {
+	return "MAIN\tSTART\n" + body + "\n\tRET\n\tEND\n"
+}
+
+func TestStep(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		gr   map[int]uint16
+		fr   Flags
+	}{
+		{"LD", "\tLD\tGR1,=#FFFF", map[int]uint16{1: 0xFFFF}, Flags{SF: true}},
+		{"LD r1,r2", "\tLAD\tGR2,0\n\tLD\tGR1,GR2", map[int]uint16{1: 0}, Flags{ZF: true}},
+		{"LAD x", "\tLAD\tGR2,2\n\tLAD\tGR1,3,GR2", map[int]uint16{1: 5}, Flags{}},
+		{"ADDA overflow", "\tLAD\tGR1,32767\n\tADDA\tGR1,=1", map[int]uint16{1: 0x8000}, Flags{OF: true, SF: true}},
+		{"ADDA r1,r2", "\tLAD\tGR1,-5\n\tLAD\tGR2,5\n\tADDA\tGR1,GR2", map[int]uint16{1: 0}, Flags{ZF: true}},
+		{"SUBA overflow", "\tLAD\tGR1,#8000\n\tSUBA\tGR1,=1", map[int]uint16{1: 0x7FFF}, Flags{OF: true}},
+		{"ADDL carry", "\tLAD\tGR1,#FFFF\n\tADDL\tGR1,=1", map[int]uint16{1: 0}, Flags{OF: true, ZF: true}},
+		{"SUBL borrow", "\tLAD\tGR1,0\n\tSUBL\tGR1,=1", map[int]uint16{1: 0xFFFF}, Flags{OF: true, SF: true}},
+		{"AND", "\tLAD\tGR1,#00FF\n\tAND\tGR1,=#0F0F", map[int]uint16{1: 0x000F}, Flags{}},
+		{"OR", "\tLAD\tGR1,#8000\n\tLAD\tGR2,1\n\tOR\tGR1,GR2", map[int]uint16{1: 0x8001}, Flags{SF: true}},
+		{"XOR", "\tLAD\tGR1,#1234\n\tXOR\tGR1,GR1", map[int]uint16{1: 0}, Flags{ZF: true}},
+		{"CPA", "\tLAD\tGR1,-1\n\tCPA\tGR1,=1", map[int]uint16{1: 0xFFFF}, Flags{SF: true}},
+		{"CPL", "\tLAD\tGR1,-1\n\tCPL\tGR1,=1", map[int]uint16{1: 0xFFFF}, Flags{}},
+		{"CPA equal", "\tLAD\tGR1,7\n\tLAD\tGR2,7\n\tCPA\tGR1,GR2", map[int]uint16{1: 7}, Flags{ZF: true}},
+		{"SLA", "\tLD\tGR1,=#C001\n\tSLA\tGR1,1", map[int]uint16{1: 0x8002}, Flags{OF: true, SF: true}},
+		{"SRA", "\tLD\tGR1,=#8001\n\tSRA\tGR1,1", map[int]uint16{1: 0xC000}, Flags{OF: true, SF: true}},
+		{"SLL", "\tLD\tGR1,=#8001\n\tSLL\tGR1,1", map[int]uint16{1: 0x0002}, Flags{OF: true}},
+		{"SRL", "\tLD\tGR1,=#8001\n\tSRL\tGR1,16", map[int]uint16{1: 0}, Flags{OF: true, ZF: true}},
+		{"SLL x", "\tLAD\tGR1,1\n\tLAD\tGR2,4\n\tSLL\tGR1,0,GR2", map[int]uint16{1: 16}, Flags{}},
+		{"JMI", "\tLD\tGR1,=#FFFF\n\tJMI\tL\n\tLAD\tGR2,1\nL\tLAD\tGR3,1", map[int]uint16{2: 0, 3: 1}, Flags{SF: true}},
+		{"JNZ", "\tLD\tGR1,=0\n\tJNZ\tL\n\tLAD\tGR2,1\nL\tLAD\tGR3,1", map[int]uint16{2: 1, 3: 1}, Flags{ZF: true}},
+		{"JZE", "\tLD\tGR1,=0\n\tJZE\tL\n\tLAD\tGR2,1\nL\tLAD\tGR3,1", map[int]uint16{2: 0, 3: 1}, Flags{ZF: true}},
+		{"JPL", "\tLD\tGR1,=1\n\tJPL\tL\n\tLAD\tGR2,1\nL\tLAD\tGR3,1", map[int]uint16{2: 0, 3: 1}, Flags{}},
+		{"JOV", "\tLAD\tGR1,#FFFF\n\tADDL\tGR1,=1\n\tJOV\tL\n\tLAD\tGR2,1\nL\tLAD\tGR3,1", map[int]uint16{2: 0, 3: 1}, Flags{OF: true, ZF: true}},
+		{"JUMP", "\tJUMP\tL\n\tLAD\tGR2,1\nL\tLAD\tGR3,1", map[int]uint16{2: 0, 3: 1}, Flags{}},
+		{"PUSH POP", "\tLAD\tGR2,3\n\tPUSH\t5,GR2\n\tPOP\tGR1", map[int]uint16{1: 8}, Flags{}},
+		{"CALL RET", "\tCALL\tSUB\n\tLAD\tGR2,1\n\tRET\nSUB\tLAD\tGR1,7\n\tRET", map[int]uint16{1: 7, 2: 1}, Flags{}},
+	}
+	for _, tt := range tests {
+		m := assemble(t, program(tt.body))
+		if err := m.Run(1000); err != nil {
+			t.Errorf("%s : %v", tt.name, err)
+			continue
+		}
+		for r, want := range tt.gr {
+			if m.GR[r] != want {
+				t.Errorf("%s : GR%d = #%04X, want #%04X", tt.name, r, m.GR[r], want)
+			}
+		}
+		if m.FR != tt.fr {
+			t.Errorf("%s : FR = %+v, want %+v", tt.name, m.FR, tt.fr)
+		}
+		if !m.Halted() || m.SP != 0 {
+			t.Errorf("%s : halted %v SP #%04X", tt.name, m.Halted(), m.SP)
+		}
+	}
+}
+
+func TestStepError(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []uint16
+	}{
+		{"unknown code", []uint16{0xFF00}},
+		{"register", []uint16{0x1090, 0x0000}},
+		{"index", []uint16{0x1418}},
+	}
+	for _, tt := range tests {
+		m := New()
+		m.LoadImage(tt.words, 0)
+		if err := m.Step(); err == nil {
+			t.Errorf("%s : エラーになりません", tt.name)
+		}
+	}
+}
+
+func TestRunLimit(t *testing.T) {
+	m := assemble(t, program("L\tJUMP\tL"))
+	if err := m.Run(100); err == nil || m.Steps != 100 {
+		t.Fatalf("err %v steps %d", err, m.Steps)
+	}
+	m = assemble(t, program("\tLAD\tGR1,1"))
+	if err := m.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Step(); err == nil {
+		t.Fatal("終了後の Step がエラーになりません")
+	}
+}
@@ -0,0 +1,106 @@
+package comet2
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Undo 1命令分の取り消し情報 (実行前のレジスタ・FR と書き換えたメモリの旧値)
+type Undo struct {
+	Step   int
+	GR     [8]uint16
+	SP     uint16
+	PR     uint16
+	FR     Flags
+	Halted bool
+	Memory []MemoryWrite
+	Input  string //IN で読み込んだ入力
+}
+
+// MemoryWrite 書き換え前のメモリ
+type MemoryWrite struct {
+	Addr uint16
+	Old  uint16
+}
+
+// SetHistoryLimit 取り消し情報を最大 limit 命令分記録する。0 で記録しない
+func (m *Machine) SetHistoryLimit(limit int) {
+	m.historyLimit = limit
+	if limit <= 0 {
+		m.history = nil
+		return
+	}
+	if len(m.history) > limit {
+		m.history = append([]Undo(nil), m.history[len(m.history)-limit:]...)
+	}
+}
+
+// History 記録されている取り消し情報の数
+func (m *Machine) History() int {
+	return len(m.history)
+}
+
+// StepBack 直前の1命令を取り消す
+// OUT で出力済みの文字は取り消されない
+func (m *Machine) StepBack() error {
+	if len(m.history) == 0 {
+		return fmt.Errorf("これ以上戻れません。")
+	}
+	u := m.history[len(m.history)-1]
+	m.history = m.history[:len(m.history)-1]
+	for i := len(u.Memory) - 1; i >= 0; i-- {
+		m.Memory[u.Memory[i].Addr] = u.Memory[i].Old
+	}
+	if u.Input != "" {
+		m.in = bufio.NewReader(io.MultiReader(strings.NewReader(u.Input), m.in))
+	}
+	m.GR, m.SP, m.PR, m.FR = u.GR, u.SP, u.PR, u.FR
+	m.halted = u.Halted
+	m.Steps = u.Step
+	m.stop = nil
+	return nil
+}
+
+// GoToStep 実行命令数が step になるまで戻る・進む
+func (m *Machine) GoToStep(limit int, step int) error {
+	if step < 0 {
+		return fmt.Errorf("%d : 実行命令数が不正です。", step)
+	}
+	if m.Steps-step > len(m.history) {
+		return fmt.Errorf("%d命令目までは戻れません。戻れるのは%d命令目までです。", step, m.Steps-len(m.history))
+	}
+	for m.Steps > step {
+		if err := m.StepBack(); err != nil {
+			return err
+		}
+	}
+	if m.Steps == step {
+		return nil
+	}
+	return m.RunUntil(limit, func(m *Machine) bool {
+		return m.Steps >= step
+	})
+}
+
+// record 取り消し情報の記録を開始する
+func (m *Machine) record() {
+	if m.historyLimit <= 0 {
+		m.undo = nil
+		return
+	}
+	m.undo = &Undo{Step: m.Steps, GR: m.GR, SP: m.SP, PR: m.PR, FR: m.FR, Halted: m.halted}
+}
+
+// commit 記録した取り消し情報を履歴に追加する
+func (m *Machine) commit() {
+	if m.undo == nil {
+		return
+	}
+	if len(m.history) >= m.historyLimit {
+		m.history = m.history[len(m.history)-m.historyLimit+1:]
+	}
+	m.history = append(m.history, *m.undo)
+	m.undo = nil
+}
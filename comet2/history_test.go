@@ -0,0 +1,150 @@
+package comet2
+
+import (
+	"strings"
+	"testing"
+)
+
+const reversible = `MAIN	START
+	LAD	GR1,2
+LOOP	ST	GR1,X
+	ADDA	GR1,=#FFFF
+	JNZ	LOOP
+	CALL	SUB
+	IN	BUF,LEN
+	RET
+SUB	PUSH	0,GR1
+	POP	GR2
+	RET
+X	DS	1
+BUF	DS	256
+LEN	DS	1
+	END
+`
+
+// state StepBack で戻る状態
+type state struct {
+	GR     [8]uint16
+	SP, PR uint16
+	FR     Flags
+	Steps  int
+	Halted bool
+	Memory [MemorySize]uint16
+}
+
+func snapshot(m *Machine) state {
+	return state{m.GR, m.SP, m.PR, m.FR, m.Steps, m.halted, m.Memory}
+}
+
+func TestStepBack(t *testing.T) {
+	m := assemble(t, reversible)
+	m.Stdin = strings.NewReader("ab\ncd\n")
+	m.SetHistoryLimit(1000)
+	var states []state
+	for !m.Halted() {
+		states = append(states, snapshot(m))
+		if err := m.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	final := snapshot(m)
+	if m.History() != len(states) {
+		t.Fatalf("history %d, want %d", m.History(), len(states))
+	}
+	for i := len(states) - 1; i >= 0; i-- {
+		if err := m.StepBack(); err != nil {
+			t.Fatalf("%d : %v", i, err)
+		}
+		if snapshot(m) != states[i] {
+			t.Fatalf("%d命令目に戻っていません PR #%04X, want #%04X", i, m.PR, states[i].PR)
+		}
+	}
+	if err := m.StepBack(); err == nil {
+		t.Fatal("最初の命令より前に戻れます")
+	}
+	// 取り消した IN の入力はもう一度読み込まれる
+	if err := m.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot(m) != final {
+		t.Fatal("やり直した結果が一致しません")
+	}
+}
+
+func TestGoToStep(t *testing.T) {
+	tests := []struct {
+		limit int
+		steps []int
+		err   bool
+	}{
+		{1000, []int{10, 3, 0, 12}, false},
+		{1000, []int{-1}, true},
+		{5, []int{15, 10}, false},
+		{5, []int{15, 9}, true},
+		{0, []int{8, 7}, true},
+	}
+	for _, tt := range tests {
+		m := assemble(t, reversible)
+		m.Stdin = strings.NewReader("ab\n")
+		m.SetHistoryLimit(tt.limit)
+		var err error
+		for _, step := range tt.steps {
+			if err = m.GoToStep(1000, step); err != nil {
+				break
+			}
+			if m.Steps != step {
+				t.Errorf("%d %v : steps %d", tt.limit, tt.steps, m.Steps)
+			}
+		}
+		if (err != nil) != tt.err {
+			t.Errorf("%d %v : err %v", tt.limit, tt.steps, err)
+		}
+		if tt.limit > 0 && m.History() > tt.limit {
+			t.Errorf("%d %v : history %d", tt.limit, tt.steps, m.History())
+		}
+	}
+}
+
+func TestSetHistoryLimit(t *testing.T) {
+	m := assemble(t, reversible)
+	m.Stdin = strings.NewReader("")
+	m.SetHistoryLimit(100)
+	m.Run(0)
+	n := m.Steps
+	m.SetHistoryLimit(3)
+	if m.History() != 3 {
+		t.Fatalf("history %d", m.History())
+	}
+	for i := 0; i < 3; i++ {
+		m.StepBack()
+	}
+	if m.Steps != n-3 || m.StepBack() == nil {
+		t.Fatalf("steps %d", m.Steps)
+	}
+	m.SetHistoryLimit(0)
+	m.Step()
+	if m.History() != 0 {
+		t.Fatalf("history %d", m.History())
+	}
+}
+
+// TestStepErrorHistory 実行できなかった命令は履歴に残らず、戻れる命令数はずれない
+func TestStepErrorHistory(t *testing.T) {
+	m := New()
+	m.LoadImage([]uint16{0x1210, 0x0001, 0xFF00}, 0)
+	m.SetHistoryLimit(10)
+	if err := m.Step(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := m.Step(); err == nil {
+			t.Fatal("エラーになりません")
+		}
+	}
+	if m.History() != 1 || m.Steps != 1 {
+		t.Fatalf("history %d steps %d", m.History(), m.Steps)
+	}
+	if err := m.GoToStep(0, 0); err != nil || m.PR != 0 || m.GR[1] != 0 {
+		t.Fatalf("%v PR #%04X GR1 #%04X", err, m.PR, m.GR[1])
+	}
+}
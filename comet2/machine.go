@@ -0,0 +1,161 @@
+package comet2
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+)
+
+// MemorySize COMET II main memory size (word)
+const MemorySize = 65536
+
+// Flags FR (Flag Register)
+type Flags struct {
+	OF bool //overflow flag
+	SF bool //sign flag
+	ZF bool //zero flag
+}
+
+// Machine COMET II virtual machine
+type Machine struct {
+	Memory   [MemorySize]uint16
+	GR       [8]uint16
+	SP       uint16
+	PR       uint16
+	FR       Flags
+	Steps    int       //executed instruction count
+	Stdin    io.Reader //IN input
+	Stdout   io.Writer //OUT output
+	Tracer   Tracer    //実行トレースの出力先
+	in       *bufio.Reader
+	svcTable map[uint16]SVCHandler
+	lines    map[uint16]int //address → source line
+	lineAddr map[int]uint16 //source line → address
+	halted   bool
+
+	breakpoints map[uint16]bool
+	watchpoints []Watchpoint
+	stop        *Stop
+
+	history      []Undo
+	historyLimit int
+	undo         *Undo
+
+	trace *TraceStep
+}
+
+// New COMET II init
+func New() *Machine {
+	return &Machine{}
+}
+
+// Load ParseProgram・LabelToAddress の結果をメモリに配置し、PR を最初の START ラベル (START のオペランドがあればその番地) に設定する
+// START のないプログラム (`LD GR1,GR2` だけのソースなど) は0番地から実行する
+func (m *Machine) Load(code []opcode.Opcode) error {
+	var addr uint16
+	start := -1
+	m.lines = map[uint16]int{}
+	m.lineAddr = map[int]uint16{}
+	for _, op := range code {
+		if op.Token.Type == token.START && op.Label != nil && start < 0 {
+			start = int(op.Label.Address)
+		}
+		if line := op.Token.Line; line > 0 && op.Length > 0 {
+			m.lines[addr] = line
+			if _, ok := m.lineAddr[line]; !ok {
+				m.lineAddr[line] = addr
+			}
+		}
+		for _, w := range op.Words() {
+			m.Memory[addr] = w
+			addr++
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	m.reset(uint16(start))
+	return nil
+}
+
+// LoadImage リンク済みの語列を0番地から配置し、PR を entry に設定する
+func (m *Machine) LoadImage(code []uint16, entry uint16) error {
+	if len(code) > MemorySize {
+		return fmt.Errorf("プログラムが大きすぎます。%d語", len(code))
+	}
+	m.lines = map[uint16]int{}
+	m.lineAddr = map[int]uint16{}
+	copy(m.Memory[:], code)
+	m.reset(entry)
+	return nil
+}
+
+func (m *Machine) reset(entry uint16) {
+	m.PR = entry
+	m.SP = 0
+	m.halted = false
+	m.history = nil
+}
+
+// Halted プログラムが終了しているか
+func (m *Machine) Halted() bool {
+	return m.halted
+}
+
+// Run 停止するまで実行する。limit が 0 より大きい場合は命令数の上限
+func (m *Machine) Run(limit int) error {
+	return m.RunUntil(limit, nil)
+}
+
+// RunUntil 命令を実行するたびに stop を呼び、true を返すか停止するまで実行する
+// ブレークポイント (最初の1命令を除く)・watchpoint でも停止する
+func (m *Machine) RunUntil(limit int, stop func(m *Machine) bool) error {
+	m.stop = nil
+	for n := 0; !m.halted; n++ {
+		if n > 0 && m.breakpoints[m.PR] {
+			m.stop = &Stop{Reason: "breakpoint", Addr: m.PR}
+			return nil
+		}
+		if limit > 0 && n >= limit {
+			return fmt.Errorf("実行命令数が上限(%d)を超えました。無限ループの可能性があります。", limit)
+		}
+		if err := m.Step(); err != nil {
+			return err
+		}
+		if m.stop != nil {
+			return nil
+		}
+		if stop != nil && stop(m) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// effectiveAddress adr [,x] の実行アドレス
+func (m *Machine) effectiveAddress(adr uint16, x uint16) uint16 {
+	if x == 0 {
+		return adr
+	}
+	return adr + m.GR[x]
+}
+
+func (m *Machine) setFlags(v uint16, of bool) {
+	m.FR.OF = of
+	m.FR.SF = v&0x8000 != 0
+	m.FR.ZF = v == 0
+}
+
+func (m *Machine) push(v uint16) {
+	m.SP--
+	m.write(m.SP, v)
+}
+
+func (m *Machine) pop() uint16 {
+	v := m.read(m.SP)
+	m.SP++
+	return v
+}
@@ -0,0 +1,108 @@
+package comet2
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SVC number (parser の IN・OUT マクロ展開先)
+const (
+	SVCIn  = 0x703A
+	SVCOut = 0x02AB
+)
+
+// InputLimit IN・OUT で1回に入出力できる文字数
+const InputLimit = 256
+
+// SVCHandler SVC 命令の処理
+// SVC 実行時の GR・メモリを参照・変更できる
+type SVCHandler func(m *Machine) error
+
+// defaultSVC 標準の SVC テーブル
+var defaultSVC = map[uint16]SVCHandler{
+	SVCIn:  (*Machine).svcIn,
+	SVCOut: (*Machine).svcOut,
+}
+
+// HandleSVC SVC 番号にハンドラを登録する。nil を渡すと登録を解除する
+func (m *Machine) HandleSVC(n uint16, h SVCHandler) {
+	if m.svcTable == nil {
+		m.svcTable = map[uint16]SVCHandler{}
+		for k, v := range defaultSVC {
+			m.svcTable[k] = v
+		}
+	}
+	if h == nil {
+		delete(m.svcTable, n)
+		return
+	}
+	m.svcTable[n] = h
+}
+
+// svc SVC adr
+func (m *Machine) svc(n uint16) error {
+	table := m.svcTable
+	if table == nil {
+		table = defaultSVC
+	}
+	h, ok := table[n]
+	if !ok {
+		return fmt.Errorf("SVC #%04X は未対応です。PR : #%04X", n, m.PR)
+	}
+	return h(m)
+}
+
+// svcIn 入力装置から1レコード読み込む
+// GR1:入力領域の先頭アドレス GR2:入力文字長のアドレス
+// 257文字目以降は切り捨て、EOF では文字長に -1 を格納する
+func (m *Machine) svcIn() error {
+	if m.in == nil {
+		if m.Stdin == nil {
+			m.Stdin = strings.NewReader("")
+		}
+		m.in = bufio.NewReader(m.Stdin)
+	}
+	line, err := m.in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if m.undo != nil {
+		m.undo.Input = line
+	}
+	buf, lenAddr := m.GR[1], m.GR[2]
+	if err == io.EOF && len(line) == 0 {
+		m.write(lenAddr, 0xFFFF)
+		return nil
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) > InputLimit {
+		line = line[:InputLimit]
+	}
+	for i := 0; i < len(line); i++ {
+		m.write(buf+uint16(i), uint16(line[i]))
+	}
+	m.write(lenAddr, uint16(len(line)))
+	return nil
+}
+
+// svcOut 出力装置へ1レコード書き出す
+// GR1:出力領域の先頭アドレス GR2:出力文字長のアドレス
+// 文字長は 0〜256 (IN が EOF で格納した -1 などはエラー)
+func (m *Machine) svcOut() error {
+	buf, length := m.GR[1], m.read(m.GR[2])
+	if length > InputLimit {
+		return fmt.Errorf("出力文字長 %d が範囲外です。0~%dで指定してください。PR : #%04X", int16(length), InputLimit, m.PR)
+	}
+	if m.Stdout == nil {
+		return nil
+	}
+	b := make([]byte, 0, int(length)+1)
+	for i := uint16(0); i < length; i++ {
+		b = append(b, byte(m.read(buf+i)))
+	}
+	b = append(b, '\n')
+	_, err := m.Stdout.Write(b)
+	return err
+}
@@ -0,0 +1,110 @@
+package comet2
+
+import (
+	"strings"
+	"testing"
+)
+
+const echo = `MAIN	START
+	IN	BUF,LEN
+	LD	GR3,LEN
+	RET
+BUF	DS	300
+LEN	DS	1
+	END
+`
+
+func TestIn(t *testing.T) {
+	tests := []struct {
+		input  string
+		length uint16
+		buf    string
+	}{
+		{"abc\n", 3, "abc"},
+		{"abc", 3, "abc"},
+		{"a\r\nb\n", 1, "a"},
+		{"\n", 0, ""},
+		{"", 0xFFFF, ""},
+		{strings.Repeat("x", 300), InputLimit, strings.Repeat("x", InputLimit)},
+	}
+	for _, tt := range tests {
+		m := assemble(t, echo)
+		m.Stdin = strings.NewReader(tt.input)
+		if err := m.Run(0); err != nil {
+			t.Errorf("%q : %v", tt.input, err)
+			continue
+		}
+		if m.GR[3] != tt.length {
+			t.Errorf("%q : 文字長 %d, want %d", tt.input, int16(m.GR[3]), int16(tt.length))
+		}
+		buf := m.lineAddr[5]
+		for i := 0; i < len(tt.buf); i++ {
+			if m.Memory[int(buf)+i] != uint16(tt.buf[i]) {
+				t.Errorf("%q : %d文字目 #%04X", tt.input, i, m.Memory[int(buf)+i])
+				break
+			}
+		}
+		if n := len(tt.buf); m.Memory[int(buf)+n] != 0 {
+			t.Errorf("%q : %d文字目以降に書き込んでいます", tt.input, n)
+		}
+	}
+}
+
+func TestInLines(t *testing.T) {
+	m := assemble(t, "MAIN\tSTART\n\tIN\tBUF,L1\n\tIN\tBUF,L2\n\tIN\tBUF,L3\n\tRET\nBUF\tDS\t256\nL1\tDS\t1\nL2\tDS\t1\nL3\tDS\t1\n\tEND\n")
+	m.Stdin = strings.NewReader("ab\ncde")
+	if err := m.Run(0); err != nil {
+		t.Fatal(err)
+	}
+	l1 := m.lineAddr[7]
+	if got := [3]uint16{m.Memory[l1], m.Memory[l1+1], m.Memory[l1+2]}; got != [3]uint16{2, 3, 0xFFFF} {
+		t.Fatalf("文字長 %v", got)
+	}
+}
+
+func TestOut(t *testing.T) {
+	tests := []struct {
+		length string
+		output string
+		err    bool
+	}{
+		{"0", "\n", false},
+		{"3", "ABC\n", false},
+		{"256", "ABC" + strings.Repeat("\x00", 253) + "\n", false},
+		{"257", "", true},
+		{"-1", "", true},
+	}
+	for _, tt := range tests {
+		m := assemble(t, "MAIN\tSTART\n\tOUT\tBUF,LEN\n\tRET\nBUF\tDC\t'ABC'\n\tDS\t253\nLEN\tDC\t"+tt.length+"\n\tEND\n")
+		var out strings.Builder
+		m.Stdout = &out
+		err := m.Run(0)
+		if (err != nil) != tt.err {
+			t.Errorf("%s : err %v", tt.length, err)
+		}
+		if out.String() != tt.output {
+			t.Errorf("%s : output %q, want %q", tt.length, out.String(), tt.output)
+		}
+	}
+}
+
+func TestHandleSVC(t *testing.T) {
+	src := "MAIN\tSTART\n\tLAD\tGR1,1\n\tSVC\t5\n\tRET\n\tEND\n"
+	m := assemble(t, src)
+	if err := m.Run(0); err == nil {
+		t.Fatal("未登録の SVC がエラーになりません")
+	}
+	m = assemble(t, src)
+	m.HandleSVC(5, func(m *Machine) error {
+		m.GR[2] = m.GR[1] + 1
+		return nil
+	})
+	if err := m.Run(0); err != nil || m.GR[2] != 2 {
+		t.Fatalf("err %v GR2 %d", err, m.GR[2])
+	}
+	m = assemble(t, "MAIN\tSTART\n\tOUT\tBUF,LEN\n\tRET\nBUF\tDC\t'A'\nLEN\tDC\t1\n\tEND\n")
+	m.HandleSVC(SVCOut, nil)
+	if err := m.Run(0); err == nil {
+		t.Fatal("登録を解除した SVC がエラーになりません")
+	}
+}
@@ -0,0 +1,161 @@
+package comet2
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+)
+
+// registerNames trace で使うレジスタ名 (GR0~GR7, SP)
+var registerNames = [9]string{"GR0", "GR1", "GR2", "GR3", "GR4", "GR5", "GR6", "GR7", "SP"}
+
+// TraceStep 1命令分の実行トレース
+type TraceStep struct {
+	Step     int               `json:"step"`
+	PR       uint16            `json:"PR"`
+	Mnemonic string            `json:"mnemonic"`
+	Addr     *uint16           `json:"addr,omitempty"` //実行アドレス
+	GR       map[string]uint16 `json:"GR,omitempty"`   //書き込まれたレジスタ (SP を含む)
+	Memory   []MemoryValue     `json:"memory,omitempty"`
+	FR       Flags             `json:"FR"`
+}
+
+// MemoryValue 書き込まれたメモリ
+type MemoryValue struct {
+	Addr  uint16 `json:"addr"`
+	Value uint16 `json:"value"`
+}
+
+// Tracer 実行トレースの出力先
+type Tracer interface {
+	Trace(t *TraceStep) error
+}
+
+// traceStep step を実行し、Tracer に実行トレースを渡す
+func (m *Machine) traceStep() error {
+	word := m.Memory[m.PR]
+	inst, _ := opcode.Lookup(uint8(word >> 8))
+	t := &TraceStep{PR: m.PR, Mnemonic: inst.Mnemonic}
+	gr, sp := m.GR, m.SP
+	m.trace = t
+	err := m.step()
+	m.trace = nil
+	if err != nil {
+		return err
+	}
+	t.Step = m.Steps
+	t.FR = m.FR
+	dest := -1
+	if inst.Has(opcode.WritesRegister) {
+		dest = int(word>>4) & 0x0F
+	}
+	for i, v := range m.GR {
+		if v != gr[i] || i == dest {
+			t.setRegister(registerNames[i], v)
+		}
+	}
+	if m.SP != sp {
+		t.setRegister(registerNames[8], m.SP)
+	}
+	return m.Tracer.Trace(t)
+}
+
+func (t *TraceStep) setRegister(name string, v uint16) {
+	if t.GR == nil {
+		t.GR = map[string]uint16{}
+	}
+	t.GR[name] = v
+}
+
+type jsonlTracer struct {
+	enc *json.Encoder
+}
+
+// NewJSONLTracer 1命令を1行の JSON で出力する (JSON Lines)
+func NewJSONLTracer(w io.Writer) Tracer {
+	return &jsonlTracer{enc: json.NewEncoder(w)}
+}
+
+func (t *jsonlTracer) Trace(s *TraceStep) error {
+	return t.enc.Encode(s)
+}
+
+type csvTracer struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVTracer 1命令を1行の CSV で出力する
+// step,PR,mnemonic,addr,GR,memory,OF,SF,ZF,output,error (GR・memory は "GR1=#0001;SP=#FFFF" の形式)
+// output・error は WriteCSVResult の最終行のみ
+func NewCSVTracer(w io.Writer) Tracer {
+	return &csvTracer{w: csv.NewWriter(w)}
+}
+
+func (t *csvTracer) writeHeader() {
+	if !t.header {
+		t.header = true
+		t.w.Write([]string{"step", "PR", "mnemonic", "addr", "GR", "memory", "OF", "SF", "ZF", "output", "error"})
+	}
+}
+
+func (t *csvTracer) Trace(s *TraceStep) error {
+	t.writeHeader()
+	var addr string
+	if s.Addr != nil {
+		addr = fmt.Sprintf("#%04X", *s.Addr)
+	}
+	var regs, mem []string
+	for _, name := range registerNames {
+		if v, ok := s.GR[name]; ok {
+			regs = append(regs, fmt.Sprintf("%s=#%04X", name, v))
+		}
+	}
+	for _, w := range s.Memory {
+		mem = append(mem, fmt.Sprintf("#%04X=#%04X", w.Addr, w.Value))
+	}
+	t.w.Write([]string{
+		fmt.Sprint(s.Step), fmt.Sprintf("#%04X", s.PR), s.Mnemonic, addr,
+		strings.Join(regs, ";"), strings.Join(mem, ";"),
+		flagBit(s.FR.OF), flagBit(s.FR.SF), flagBit(s.FR.ZF), "", "",
+	})
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// WriteCSVResult 実行結果を NewCSVTracer の最終行にする (JSON Lines の最終行の実行結果にあたる)
+// step・PR・FR は終了時の値、mnemonic は OK・NG、GR は全レジスタと SP、output は OUT の出力、error は実行時エラー
+func WriteCSVResult(tracer Tracer, m *Machine, output string, runErr error) error {
+	t, ok := tracer.(*csvTracer)
+	if !ok {
+		return fmt.Errorf("CSV の Tracer ではありません。")
+	}
+	t.writeHeader()
+	result, msg := "OK", ""
+	if runErr != nil {
+		result, msg = "NG", runErr.Error()
+	}
+	regs := make([]string, 0, len(registerNames))
+	for i, v := range m.GR {
+		regs = append(regs, fmt.Sprintf("%s=#%04X", registerNames[i], v))
+	}
+	regs = append(regs, fmt.Sprintf("%s=#%04X", registerNames[8], m.SP))
+	t.w.Write([]string{
+		fmt.Sprint(m.Steps), fmt.Sprintf("#%04X", m.PR), result, "",
+		strings.Join(regs, ";"), "",
+		flagBit(m.FR.OF), flagBit(m.FR.SF), flagBit(m.FR.ZF), output, msg,
+	})
+	t.w.Flush()
+	return t.w.Error()
+}
+
+func flagBit(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,100 @@
+package comet2
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// traceSteps src を JSONL で trace し、各行を返す
+func traceSteps(t *testing.T, src string) []TraceStep {
+	t.Helper()
+	m := assemble(t, src)
+	var buf bytes.Buffer
+	m.Tracer = NewJSONLTracer(&buf)
+	if err := m.Run(100); err != nil {
+		t.Fatal(err)
+	}
+	var steps []TraceStep
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var s TraceStep
+		if err := dec.Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		steps = append(steps, s)
+	}
+	if len(steps) != m.Steps {
+		t.Fatalf("%d行, want %d", len(steps), m.Steps)
+	}
+	return steps
+}
+
+func addr(a uint16) *uint16 {
+	return &a
+}
+
+func TestTrace(t *testing.T) {
+	// 0:START 1:命令 3:RET 4:X
+	tests := []struct {
+		name string
+		body string
+		want TraceStep
+	}{
+		{"LAD", "\tLAD\tGR1,5", TraceStep{Step: 2, PR: 1, Mnemonic: "LAD", Addr: addr(5), GR: map[string]uint16{"GR1": 5}}},
+		// 値が変わらなくても書き込んだレジスタは出力する
+		{"LD same", "\tLD\tGR0,X", TraceStep{Step: 2, PR: 1, Mnemonic: "LD", Addr: addr(4), GR: map[string]uint16{"GR0": 0}, FR: Flags{ZF: true}}},
+		{"ST", "\tST\tGR0,X", TraceStep{Step: 2, PR: 1, Mnemonic: "ST", Addr: addr(4), Memory: []MemoryValue{{Addr: 4, Value: 0}}}},
+		{"CPA", "\tCPA\tGR0,X", TraceStep{Step: 2, PR: 1, Mnemonic: "CPA", Addr: addr(4), FR: Flags{ZF: true}}},
+		{"LD r1,r2", "\tLD\tGR1,GR0", TraceStep{Step: 2, PR: 1, Mnemonic: "LD", GR: map[string]uint16{"GR1": 0}, FR: Flags{ZF: true}}},
+		{"PUSH", "\tPUSH\t3", TraceStep{Step: 2, PR: 1, Mnemonic: "PUSH", Addr: addr(3), GR: map[string]uint16{"SP": 0xFFFF}, Memory: []MemoryValue{{Addr: 0xFFFF, Value: 3}}}},
+	}
+	for _, tt := range tests {
+		steps := traceSteps(t, program(tt.body+"\n\tRET\nX\tDC\t0"))
+		if got := steps[1]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s : %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTraceCSV(t *testing.T) {
+	m := assemble(t, program("\tLAD\tGR1,1\n\tST\tGR1,X\n\tRET\nX\tDS\t1"))
+	var buf bytes.Buffer
+	m.Tracer = NewCSVTracer(&buf)
+	err := m.Run(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	WriteCSVResult(m.Tracer, m, "out\n", errors.New("err, \"x\""))
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"step", "PR", "mnemonic", "addr", "GR", "memory", "OF", "SF", "ZF", "output", "error"},
+		{"1", "#0000", "NOP", "", "", "", "0", "0", "0", "", ""},
+		{"2", "#0001", "LAD", "#0001", "GR1=#0001", "", "0", "0", "0", "", ""},
+		{"3", "#0003", "ST", "#0006", "", "#0006=#0001", "0", "0", "0", "", ""},
+		{"4", "#0005", "RET", "", "", "", "0", "0", "0", "", ""},
+		{"4", "#0005", "NG", "", "GR0=#0000;GR1=#0001;GR2=#0000;GR3=#0000;GR4=#0000;GR5=#0000;GR6=#0000;GR7=#0000;SP=#0000", "", "0", "0", "0", "out\n", "err, \"x\""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("%q", records)
+	}
+	if err := WriteCSVResult(NewJSONLTracer(&buf), m, "", nil); err == nil {
+		t.Fatal("JSONL の Tracer に CSV を書き込めます")
+	}
+}
+
+func TestTraceCSVNoSteps(t *testing.T) {
+	var buf bytes.Buffer
+	m := New()
+	WriteCSVResult(NewCSVTracer(&buf), m, "", nil)
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[0], "step,") {
+		t.Fatalf("%q", lines)
+	}
+}
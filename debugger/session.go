@@ -0,0 +1,302 @@
+package debugger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/comet2"
+	"github.com/DJSIer/OnlineGCASL2/disasm"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+)
+
+// StepLimit 1コマンドで実行する命令数の上限
+const StepLimit = 1000000
+
+// DisassembleLength disassemble で length を省略した場合の語数
+const DisassembleLength = 32
+
+// HistoryLimit stepBack で戻れる命令数の既定値かつ上限 (load の history で少なくできる)
+const HistoryLimit = 10000
+
+// Request debug command
+//
+//	{"command":"load","code":"...","input":"...","history":10000}
+//	{"command":"step"} {"command":"stepOver"} {"command":"continue"}
+//	{"command":"stepBack"} {"command":"goToStep","step":10}
+//	{"command":"runToLine","line":10}
+//	{"command":"setRegister","register":"GR1","value":10}
+//	{"command":"setBreakpoint","line":10} {"command":"setBreakpoint","label":"LOOP"} {"command":"clearBreakpoint","addr":16}
+//	{"command":"setWatchpoint","label":"BUF","length":256,"access":"write"} {"command":"clearWatchpoints"}
+//	{"command":"disassemble","addr":0,"length":32}
+type Request struct {
+	Command  string `json:"command"`
+	Code     string `json:"code,omitempty"`
+	Input    string `json:"input,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Label    string `json:"label,omitempty"`
+	Addr     *int   `json:"addr,omitempty"`
+	Length   int    `json:"length,omitempty"`
+	Access   string `json:"access,omitempty"` //read, write, readwrite
+	Register string `json:"register,omitempty"`
+	Value    int    `json:"value,omitempty"`
+	Step     int    `json:"step,omitempty"`
+	History  int    `json:"history,omitempty"`
+}
+
+// MemoryDelta 変更されたメモリ
+type MemoryDelta struct {
+	Addr  uint16 `json:"addr"`
+	Value uint16 `json:"value"`
+}
+
+// Response コマンド実行後の状態 (GR・memory は変更分のみ)
+type Response struct {
+	Result  string               `json:"result"`
+	Error   string               `json:"error,omitempty"`
+	Errors  []parser.ParserError `json:"errors,omitempty"`
+	Line    int                  `json:"line"`
+	PR      uint16               `json:"PR"`
+	SP      uint16               `json:"SP"`
+	FR      comet2.Flags         `json:"FR"`
+	GR      map[string]uint16    `json:"GR,omitempty"`
+	Memory  []MemoryDelta        `json:"memory,omitempty"`
+	Output  string               `json:"output,omitempty"`
+	Steps   int                  `json:"steps"`
+	History int                  `json:"history"` //stepBack で戻れる命令数
+	Halted  bool                 `json:"halted"`
+	Stop    *comet2.Stop         `json:"stop,omitempty"`
+
+	Breakpoints []uint16            `json:"breakpoints,omitempty"`
+	Watchpoints []comet2.Watchpoint `json:"watchpoints,omitempty"`
+	Disassembly []disasm.Line       `json:"disassembly,omitempty"`
+}
+
+// Session debug session
+type Session struct {
+	m       *comet2.Machine
+	symbols *symbol.SymbolTable
+	out     bytes.Buffer
+
+	Includer parser.Includer //load の INCLUDE のソース (nil なら INCLUDE できない)
+	Lang     string          //load のエラーメッセージの言語 (空なら parser.DefaultLanguage)
+}
+
+// NewSession debug session init
+func NewSession() *Session {
+	return &Session{}
+}
+
+// Handle コマンドを実行し、実行前との差分を返す
+func (s *Session) Handle(req Request) *Response {
+	if req.Command == "load" {
+		return s.load(req)
+	}
+	if s.m == nil {
+		return &Response{Result: "NG", Error: "プログラムが読み込まれていません。"}
+	}
+	before := *s.m
+	var err error
+	switch req.Command {
+	case "step":
+		err = s.m.Step()
+	case "stepOver":
+		err = s.m.StepOver(StepLimit)
+	case "continue":
+		err = s.m.Run(StepLimit)
+	case "runToLine":
+		err = s.m.RunToLine(StepLimit, req.Line)
+	case "stepBack":
+		err = s.m.StepBack()
+	case "goToStep":
+		err = s.m.GoToStep(StepLimit, req.Step)
+	case "setRegister":
+		err = s.setRegister(req.Register, req.Value)
+	case "setBreakpoint", "clearBreakpoint":
+		var addr uint16
+		if addr, err = s.address(req); err == nil {
+			if req.Command == "setBreakpoint" {
+				s.m.SetBreakpoint(addr)
+			} else {
+				s.m.ClearBreakpoint(addr)
+			}
+		}
+	case "setWatchpoint":
+		err = s.setWatchpoint(req)
+	case "clearWatchpoints":
+		s.m.ClearWatchpoints()
+	case "disassemble":
+		return s.disassemble(req)
+	default:
+		err = fmt.Errorf("%q : 不明なコマンドです。", req.Command)
+	}
+	res := s.delta(&before)
+	switch req.Command {
+	case "setBreakpoint", "clearBreakpoint", "setWatchpoint", "clearWatchpoints":
+		res.Breakpoints = s.m.Breakpoints()
+		res.Watchpoints = s.m.Watchpoints()
+	}
+	if err != nil {
+		res.Result = "NG"
+		res.Error = err.Error()
+	}
+	return res
+}
+
+func (s *Session) load(req Request) *Response {
+	p := parser.New(lexer.New(req.Code))
+	p.SetIncluder(s.Includer)
+	code, err := p.Assemble()
+	if err != nil {
+		return &Response{Result: "NG", Error: err.Error(), Errors: parser.LocalizeErrors(p.Errors(), s.Lang)}
+	}
+	m := comet2.New()
+	if err := m.Load(code); err != nil {
+		return &Response{Result: "NG", Error: err.Error()}
+	}
+	s.out.Reset()
+	m.Stdin = strings.NewReader(req.Input)
+	m.Stdout = &s.out
+	if req.History > 0 && req.History < HistoryLimit {
+		m.SetHistoryLimit(req.History)
+	} else {
+		m.SetHistoryLimit(HistoryLimit)
+	}
+	s.m = m
+	s.symbols = p.SymbolTable()
+	return s.delta(comet2.New())
+}
+
+func (s *Session) setRegister(name string, value int) error {
+	v := uint16(value)
+	switch name {
+	case "SP":
+		s.m.SP = v
+	case "PR":
+		s.m.PR = v
+	default:
+		var n int
+		if _, err := fmt.Sscanf(name, "GR%d", &n); err != nil || n < 0 || n > 7 || len(name) != 3 {
+			return fmt.Errorf("%q : レジスタではありません。", name)
+		}
+		s.m.GR[n] = v
+	}
+	return nil
+}
+
+// address label・line・addr のいずれかで指定された番地
+func (s *Session) address(req Request) (uint16, error) {
+	switch {
+	case req.Label != "":
+		return s.labelAddress(req.Label)
+	case req.Line > 0:
+		addr, ok := s.m.LineAddress(req.Line)
+		if !ok {
+			return 0, fmt.Errorf("%d行目に命令がありません。", req.Line)
+		}
+		return addr, nil
+	case req.Addr != nil:
+		return uint16(*req.Addr), nil
+	}
+	return 0, fmt.Errorf("label・line・addr のいずれかを指定してください。")
+}
+
+// labelAddress ラベルの番地 (EQU の定数は番地ではないので指定できない)
+func (s *Session) labelAddress(name string) (uint16, error) {
+	constant := false
+	for _, sy := range s.symbols.Symbols() {
+		if sy.Label != name {
+			continue
+		}
+		if sy.Kind != symbol.KindConstant {
+			return sy.Address, nil
+		}
+		constant = true
+	}
+	if constant {
+		return 0, fmt.Errorf("%q : EQU の定数は番地として指定できません。", name)
+	}
+	return 0, fmt.Errorf("%qは解決できません", name)
+}
+
+func (s *Session) setWatchpoint(req Request) error {
+	start, err := s.address(req)
+	if err != nil {
+		return err
+	}
+	w := comet2.Watchpoint{Start: start, End: start}
+	if req.Length > 1 {
+		w.End = start + uint16(req.Length-1)
+	}
+	switch req.Access {
+	case "read":
+		w.Access = comet2.AccessRead
+	case "write":
+		w.Access = comet2.AccessWrite
+	case "", "readwrite":
+		w.Access = comet2.AccessReadWrite
+	default:
+		return fmt.Errorf("%q : access は read・write・readwrite のいずれかです。", req.Access)
+	}
+	return s.m.AddWatchpoint(w)
+}
+
+// disassemble addr (省略時は PR) から length 語を逆アセンブルする
+func (s *Session) disassemble(req Request) *Response {
+	before := *s.m
+	res := s.delta(&before)
+	addr := s.m.PR
+	if req.Addr != nil || req.Label != "" || req.Line > 0 {
+		var err error
+		if addr, err = s.address(req); err != nil {
+			res.Result = "NG"
+			res.Error = err.Error()
+			return res
+		}
+	}
+	length := req.Length
+	if length <= 0 {
+		length = DisassembleLength
+	}
+	if int(addr)+length > comet2.MemorySize {
+		length = comet2.MemorySize - int(addr)
+	}
+	res.Disassembly = disasm.New(s.symbols).Disassemble(s.m.Memory[addr:int(addr)+length], addr)
+	return res
+}
+
+// delta before との差分
+func (s *Session) delta(before *comet2.Machine) *Response {
+	m := s.m
+	res := &Response{
+		Result:  "OK",
+		Line:    m.Line(),
+		PR:      m.PR,
+		SP:      m.SP,
+		FR:      m.FR,
+		Steps:   m.Steps,
+		History: m.History(),
+		Halted:  m.Halted(),
+		Output:  s.out.String(),
+	}
+	if m.Steps != before.Steps {
+		res.Stop = m.Stopped()
+	}
+	s.out.Reset()
+	for i, v := range m.GR {
+		if v != before.GR[i] {
+			if res.GR == nil {
+				res.GR = map[string]uint16{}
+			}
+			res.GR[fmt.Sprintf("GR%d", i)] = v
+		}
+	}
+	for i, v := range m.Memory {
+		if v != before.Memory[i] {
+			res.Memory = append(res.Memory, MemoryDelta{Addr: uint16(i), Value: v})
+		}
+	}
+	return res
+}
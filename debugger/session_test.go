@@ -0,0 +1,221 @@
+package debugger
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `MAIN	START
+	LAD	GR1,1
+	CALL	SUB
+	OUT	BUF,LEN
+	LAD	GR2,2
+	RET
+SUB	LAD	GR3,3
+	ST	GR3,X
+	RET
+X	DS	1
+BUF	DC	'AB'
+LEN	DC	2
+N	EQU	3
+	END
+`
+
+// want コマンドの応答で確認する内容 (空・nil なら確認しない)
+type want struct {
+	result string
+	error  string
+	line   int
+	gr     map[string]uint16
+	output string
+	halted bool
+}
+
+// check res が w と一致するか確認する
+func check(t *testing.T, name string, res *Response, w want) {
+	t.Helper()
+	if res.Result != w.result {
+		t.Errorf("%s : result %s, want %s (%s)", name, res.Result, w.result, res.Error)
+	}
+	if !strings.Contains(res.Error, w.error) {
+		t.Errorf("%s : error %q, want %q", name, res.Error, w.error)
+	}
+	if w.line > 0 && res.Line != w.line {
+		t.Errorf("%s : line %d, want %d", name, res.Line, w.line)
+	}
+	for r, v := range w.gr {
+		if res.GR[r] != v {
+			t.Errorf("%s : %s = %d, want %d", name, r, res.GR[r], v)
+		}
+	}
+	if res.Output != w.output {
+		t.Errorf("%s : output %q, want %q", name, res.Output, w.output)
+	}
+	if res.Halted != w.halted {
+		t.Errorf("%s : halted %v", name, res.Halted)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	tests := []struct {
+		req  Request
+		want want
+	}{
+		{Request{Command: "step"}, want{result: "NG", error: "読み込まれていません"}},
+		{Request{Command: "load", Code: "MAIN\tSTART\n\tLD\tGR9,X\n\tEND\n"}, want{result: "NG"}},
+		// START は0番地の1語目
+		{Request{Command: "load", Code: sample}, want{result: "OK", line: 1}},
+		{Request{Command: "step"}, want{result: "OK", line: 2}},
+		{Request{Command: "step"}, want{result: "OK", line: 3, gr: map[string]uint16{"GR1": 1}}},
+		{Request{Command: "stepOver"}, want{result: "OK", line: 4, gr: map[string]uint16{"GR3": 3}}},
+		{Request{Command: "runToLine", Line: 5}, want{result: "OK", line: 5, output: "AB\n"}},
+		{Request{Command: "runToLine", Line: 13}, want{result: "NG", error: "13行目に命令がありません", line: 5}},
+		{Request{Command: "setRegister", Register: "GR2", Value: 7}, want{result: "OK", line: 5, gr: map[string]uint16{"GR2": 7}}},
+		{Request{Command: "setRegister", Register: "GR8", Value: 7}, want{result: "NG", error: "レジスタではありません"}},
+		{Request{Command: "setRegister", Register: "GR10", Value: 7}, want{result: "NG", error: "レジスタではありません"}},
+		{Request{Command: "jump"}, want{result: "NG", error: "不明なコマンド"}},
+		{Request{Command: "continue"}, want{result: "OK", gr: map[string]uint16{"GR2": 2}, halted: true}},
+		{Request{Command: "step"}, want{result: "NG", halted: true}},
+		{Request{Command: "load", Code: sample}, want{result: "OK", line: 1}},
+		{Request{Command: "runToLine", Line: 8}, want{result: "OK", line: 8, gr: map[string]uint16{"GR1": 1, "GR3": 3}}},
+	}
+	s := NewSession()
+	for i, tt := range tests {
+		check(t, fmt.Sprintf("%d %s", i, tt.req.Command), s.Handle(tt.req), tt.want)
+	}
+}
+
+func TestLoadInput(t *testing.T) {
+	s := NewSession()
+	src := "MAIN\tSTART\n\tIN\tBUF,LEN\n\tOUT\tBUF,LEN\n\tRET\nBUF\tDS\t256\nLEN\tDS\t1\n\tEND\n"
+	check(t, "load", s.Handle(Request{Command: "load", Code: src, Input: "hi"}), want{result: "OK", line: 1})
+	check(t, "continue", s.Handle(Request{Command: "continue"}), want{result: "OK", output: "hi\n", halted: true})
+	// load し直すと入力も読み直す
+	check(t, "reload", s.Handle(Request{Command: "load", Code: src, Input: "abc"}), want{result: "OK", line: 1})
+	check(t, "runToLine", s.Handle(Request{Command: "runToLine", Line: 4}), want{result: "OK", line: 4, output: "abc\n"})
+}
+
+func TestBreakpointCommands(t *testing.T) {
+	addr := 22
+	tests := []struct {
+		req         Request
+		result      string
+		breakpoints []uint16
+		watchpoints int
+		stop        string
+		line        int
+	}{
+		{Request{Command: "setBreakpoint", Label: "SUB"}, "OK", []uint16{20}, 0, "", 0},
+		{Request{Command: "setBreakpoint", Line: 5}, "OK", []uint16{17, 20}, 0, "", 0},
+		{Request{Command: "setBreakpoint", Addr: &addr}, "OK", []uint16{17, 20, 22}, 0, "", 0},
+		{Request{Command: "clearBreakpoint", Addr: &addr}, "OK", []uint16{17, 20}, 0, "", 0},
+		{Request{Command: "setBreakpoint", Label: "N"}, "NG", []uint16{17, 20}, 0, "", 0},
+		{Request{Command: "setBreakpoint", Label: "NONE"}, "NG", []uint16{17, 20}, 0, "", 0},
+		{Request{Command: "setBreakpoint"}, "NG", []uint16{17, 20}, 0, "", 0},
+		{Request{Command: "setWatchpoint", Label: "X", Access: "write"}, "OK", []uint16{17, 20}, 1, "", 0},
+		{Request{Command: "setWatchpoint", Label: "BUF", Length: 2, Access: "read"}, "OK", []uint16{17, 20}, 2, "", 0},
+		{Request{Command: "setWatchpoint", Label: "BUF", Access: "exec"}, "NG", []uint16{17, 20}, 2, "", 0},
+		{Request{Command: "continue"}, "OK", nil, 0, "breakpoint", 7},
+		{Request{Command: "continue"}, "OK", nil, 0, "watchpoint", 9},
+		{Request{Command: "continue"}, "OK", nil, 0, "watchpoint", 4},
+		{Request{Command: "clearWatchpoints"}, "OK", []uint16{17, 20}, 0, "", 0},
+		{Request{Command: "continue"}, "OK", nil, 0, "breakpoint", 5},
+		{Request{Command: "continue"}, "OK", nil, 0, "", 6},
+	}
+	s := NewSession()
+	s.Handle(Request{Command: "load", Code: sample})
+	for i, tt := range tests {
+		res := s.Handle(tt.req)
+		name := fmt.Sprintf("%d %s", i, tt.req.Command)
+		if res.Result != tt.result {
+			t.Errorf("%s : result %s, want %s (%s)", name, res.Result, tt.result, res.Error)
+		}
+		if tt.req.Command != "continue" {
+			if !reflect.DeepEqual(res.Breakpoints, tt.breakpoints) || len(res.Watchpoints) != tt.watchpoints {
+				t.Errorf("%s : breakpoints %v watchpoints %v", name, res.Breakpoints, res.Watchpoints)
+			}
+			continue
+		}
+		if reason := ""; res.Stop != nil {
+			reason = res.Stop.Reason
+			if reason != tt.stop {
+				t.Errorf("%s : stop %s, want %s", name, reason, tt.stop)
+			}
+		} else if tt.stop != "" {
+			t.Errorf("%s : 停止していません", name)
+		}
+		if res.Line != tt.line {
+			t.Errorf("%s : line %d, want %d", name, res.Line, tt.line)
+		}
+	}
+}
+
+func TestStepBackCommands(t *testing.T) {
+	tests := []struct {
+		req     Request
+		result  string
+		steps   int
+		history int
+	}{
+		{Request{Command: "load", Code: sample, History: 3}, "OK", 0, 0},
+		{Request{Command: "runToLine", Line: 8}, "OK", 4, 3},
+		{Request{Command: "stepBack"}, "OK", 3, 2},
+		{Request{Command: "goToStep", Step: 1}, "OK", 1, 0},
+		{Request{Command: "stepBack"}, "NG", 1, 0},
+		{Request{Command: "goToStep", Step: 6}, "OK", 6, 3},
+		{Request{Command: "goToStep", Step: 2}, "NG", 6, 3},
+		{Request{Command: "load", Code: sample}, "OK", 0, 0},
+		{Request{Command: "continue"}, "OK", 15, 15},
+		{Request{Command: "goToStep", Step: 0}, "OK", 0, 0},
+	}
+	s := NewSession()
+	for i, tt := range tests {
+		res := s.Handle(tt.req)
+		if res.Result != tt.result || res.Steps != tt.steps || res.History != tt.history {
+			t.Errorf("%d %s : result %s steps %d history %d, want %s %d %d (%s)", i, tt.req.Command, res.Result, res.Steps, res.History, tt.result, tt.steps, tt.history, res.Error)
+		}
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	s := NewSession()
+	s.Handle(Request{Command: "load", Code: sample})
+	addr := 0xFFFE
+	tests := []struct {
+		req   Request
+		lines int
+	}{
+		{Request{Command: "disassemble", Label: "SUB", Length: 5}, 3},
+		{Request{Command: "disassemble", Line: 2, Length: 2}, 1},
+		// メモリの末尾で切り詰める
+		{Request{Command: "disassemble", Addr: &addr}, 2},
+		{Request{Command: "disassemble", Label: "N"}, 0},
+	}
+	for _, tt := range tests {
+		res := s.Handle(tt.req)
+		if len(res.Disassembly) != tt.lines {
+			t.Errorf("%+v : %d行 %v %s", tt.req, len(res.Disassembly), res.Disassembly, res.Error)
+		}
+	}
+	res := s.Handle(Request{Command: "disassemble", Label: "SUB", Length: 5})
+	if got := res.Disassembly[0].String(); !strings.Contains(got, "SUB") || !strings.Contains(got, "LAD") {
+		t.Errorf("SUB : %q", got)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	tests := []struct {
+		history int
+		want    int
+	}{{0, HistoryLimit}, {-1, HistoryLimit}, {3, 3}, {HistoryLimit + 1, HistoryLimit}, {1 << 30, HistoryLimit}}
+	for _, tt := range tests {
+		s := NewSession()
+		s.Handle(Request{Command: "load", Code: "MAIN\tSTART\nL\tJUMP\tL\n\tEND\n", History: tt.history})
+		s.m.Run(HistoryLimit + 10)
+		if got := s.m.History(); got != tt.want {
+			t.Errorf("%d : history %d, want %d", tt.history, got, tt.want)
+		}
+	}
+}
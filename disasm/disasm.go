@@ -0,0 +1,137 @@
+package disasm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+)
+
+// Line 逆アセンブル結果の1行
+type Line struct {
+	Addr     uint16   `json:"addr"`
+	Words    []uint16 `json:"words"`
+	Label    string   `json:"label,omitempty"`
+	Mnemonic string   `json:"mnemonic"`
+	Operand  string   `json:"operand,omitempty"`
+}
+
+// String CASL2 ソース1行
+func (l Line) String() string {
+	if l.Operand == "" {
+		return fmt.Sprintf("%s\t%s", l.Label, l.Mnemonic)
+	}
+	return fmt.Sprintf("%s\t%s\t%s", l.Label, l.Mnemonic, l.Operand)
+}
+
+// Disassembler 語列を CASL2 の命令に戻す
+type Disassembler struct {
+	labels map[uint16]string
+}
+
+// New symbols が nil でなければ番地をラベル名で表示する
+func New(symbols *symbol.SymbolTable) *Disassembler {
+	d := &Disassembler{labels: map[uint16]string{}}
+	if symbols != nil {
+		for _, sy := range symbols.Symbols() {
+			if sy.Kind == symbol.KindConstant {
+				// EQU の定数は番地ではない
+				continue
+			}
+			if _, ok := d.labels[sy.Address]; !ok {
+				d.labels[sy.Address] = sy.Label
+			}
+		}
+	}
+	return d
+}
+
+// Disassemble words の先頭を start 番地として逆アセンブルする
+func (d *Disassembler) Disassemble(words []uint16, start uint16) []Line {
+	var lines []Line
+	for i := 0; i < len(words); {
+		l := d.Decode(words[i:], start+uint16(i))
+		lines = append(lines, l)
+		i += len(l.Words)
+	}
+	return lines
+}
+
+// Decode words の先頭の1命令を逆アセンブルする
+// 命令として解釈できない語は DC とする
+func (d *Disassembler) Decode(words []uint16, addr uint16) Line {
+	w := words[0]
+	l := Line{Addr: addr, Words: words[:1]}
+	if label := d.labels[addr]; !strings.HasPrefix(label, "=") {
+		// リテラル (=10 など) はラベル欄に書けない
+		l.Label = label
+	}
+	inst, ok := opcode.Lookup(uint8(w >> 8))
+	r, x := (w>>4)&0x0F, w&0x0F
+	if !ok || r > 7 || x > 7 || inst.Length() > len(words) || !validFields(inst.Format, r, x) {
+		l.Mnemonic = "DC"
+		l.Operand = fmt.Sprintf("#%04X", w)
+		return l
+	}
+	l.Mnemonic = inst.Mnemonic
+	l.Words = words[:inst.Length()]
+	switch inst.Format {
+	case opcode.FormatR:
+		l.Operand = register(r)
+	case opcode.FormatR1R2:
+		l.Operand = register(r) + "," + register(x)
+	case opcode.FormatRAdrX:
+		l.Operand = register(r) + "," + d.address(inst, words[1], x)
+	case opcode.FormatAdrX:
+		l.Operand = d.address(inst, words[1], x)
+	}
+	return l
+}
+
+// validFields 使用しないフィールドが 0 であるか
+func validFields(f opcode.Format, r, x uint16) bool {
+	switch f {
+	case opcode.FormatNone:
+		return r == 0 && x == 0
+	case opcode.FormatR:
+		return x == 0
+	case opcode.FormatAdrX:
+		return r == 0
+	}
+	return true
+}
+
+// address adr [,x]  シフト数・SVC 番号はラベルにしない
+func (d *Disassembler) address(inst opcode.Instruction, adr uint16, x uint16) string {
+	var s string
+	switch {
+	case inst.Code >= 0x50 && inst.Code <= 0x53 && adr < 0x8000:
+		s = fmt.Sprint(adr)
+	case inst.Code == 0xF0:
+		s = fmt.Sprintf("#%04X", adr)
+	default:
+		var ok bool
+		if s, ok = d.labels[adr]; !ok {
+			s = fmt.Sprintf("#%04X", adr)
+		}
+	}
+	if x != 0 {
+		s += "," + register(x)
+	}
+	return s
+}
+
+func register(n uint16) string {
+	return "GR" + string(rune('0'+n))
+}
+
+// Source 逆アセンブル結果を CASL2 ソースとして連結する
+func Source(lines []Line) string {
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(l.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
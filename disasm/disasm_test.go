@@ -0,0 +1,96 @@
+package disasm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		words []uint16
+		want  string
+		n     int
+	}{
+		{[]uint16{0x0000}, "\tNOP", 1},
+		{[]uint16{0x1012, 0x0010}, "\tLD\tGR1,#0010,GR2", 2},
+		{[]uint16{0x1412}, "\tLD\tGR1,GR2", 1},
+		{[]uint16{0x1270, 0xFFFF}, "\tLAD\tGR7,#FFFF", 2},
+		{[]uint16{0x5010, 0x0003}, "\tSLA\tGR1,3", 2},
+		{[]uint16{0x5310, 0x8000}, "\tSRL\tGR1,#8000", 2},
+		{[]uint16{0x6400, 0x0000}, "\tJUMP\t#0000", 2},
+		{[]uint16{0x7001, 0x0005}, "\tPUSH\t#0005,GR1", 2},
+		{[]uint16{0x7130}, "\tPOP\tGR3", 1},
+		{[]uint16{0x8100}, "\tRET", 1},
+		{[]uint16{0xF000, 0x0001}, "\tSVC\t#0001", 2},
+		// 命令として解釈できない語
+		{[]uint16{0xFF00}, "\tDC\t#FF00", 1},
+		{[]uint16{0x1090, 0x0000}, "\tDC\t#1090", 1},
+		{[]uint16{0x1018, 0x0000}, "\tDC\t#1018", 1},
+		{[]uint16{0x0010}, "\tDC\t#0010", 1},
+		{[]uint16{0x8110}, "\tDC\t#8110", 1},
+		{[]uint16{0x6410, 0x0000}, "\tDC\t#6410", 1},
+		{[]uint16{0x7101}, "\tDC\t#7101", 1},
+		{[]uint16{0x1010}, "\tDC\t#1010", 1},
+	}
+	d := New(nil)
+	for _, tt := range tests {
+		l := d.Decode(tt.words, 0)
+		if l.String() != tt.want || len(l.Words) != tt.n {
+			t.Errorf("%04X : %q (%d語), want %q (%d語)", tt.words, l.String(), len(l.Words), tt.want, tt.n)
+		}
+	}
+}
+
+const labeled = `MAIN	START
+	LD	GR1,X
+	SLA	GR1,N
+	JUMP	L
+L	ADDA	GR1,=5
+	SVC	X
+	RET
+X	DC	7
+N	EQU	3
+	END
+`
+
+func TestDisassemble(t *testing.T) {
+	p := parser.New(lexer.New(labeled))
+	code, err := p.Assemble()
+	if err != nil {
+		t.Fatal(err, p.Errors())
+	}
+	var words []uint16
+	for _, op := range code {
+		words = append(words, op.Words()...)
+	}
+	var got []string
+	for _, l := range New(p.SymbolTable()).Disassemble(words, 0) {
+		got = append(got, l.String())
+	}
+	// EQU の定数・シフト数・SVC 番号はラベルにしない
+	want := []string{
+		"MAIN\tNOP",
+		"\tLD\tGR1,X",
+		"\tSLA\tGR1,3",
+		"\tJUMP\tL",
+		"L\tADDA\tGR1,=5",
+		"\tSVC\t#000C",
+		"\tRET",
+		"X\tDC\t#0007",
+		"\tDC\t#0005",
+		"\tNOP",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%q\nwant %q", got, want)
+	}
+}
+
+func TestDisassembleTruncated(t *testing.T) {
+	lines := New(nil).Disassemble([]uint16{0x8100, 0x1010}, 0x10)
+	if len(lines) != 2 || lines[1].Addr != 0x11 || lines[1].String() != "\tDC\t#1010" {
+		t.Fatalf("%+v", lines)
+	}
+}
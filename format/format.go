@@ -0,0 +1,199 @@
+// Package format CASL2 ソースをラベル・命令・オペランド・コメントの桁にそろえる
+//
+//	LABEL     LD      GR1,=#000A          ; コメント
+//
+// コメントと空行はそのまま残す。解釈できない文字を含む行は変更しない
+package format
+
+import (
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+)
+
+// 各欄の開始桁 (0 始まり)
+const (
+	OpcodeColumn  = 10
+	OperandColumn = 18
+	CommentColumn = 38
+)
+
+// Source src を整形する。改行は LF にそろえる
+func Source(src string) string {
+	l := lexer.New(src)
+	var lines [][]token.Token
+	var line []token.Token
+	for {
+		tok := l.NextRawToken()
+		if tok.Type == token.NEWLINE || tok.Type == token.EOF {
+			lines = append(lines, line)
+			if tok.Type == token.EOF {
+				break
+			}
+			line = nil
+			continue
+		}
+		line = append(line, tok)
+	}
+	f := &formatter{macros: map[string]bool{}}
+	for _, line := range lines {
+		// マクロの本体からは後で定義するマクロも呼び出せるため、先に名前を集める
+		for i, tok := range line {
+			if tok.Type != token.WHITESPACE {
+				if next(line, i).Type == token.MACRO {
+					f.macros[tok.Literal] = true
+				}
+				break
+			}
+		}
+	}
+	formatted := make([]string, len(lines))
+	for i, line := range lines {
+		formatted[i] = f.line(line)
+	}
+	return strings.Join(formatted, "\n")
+}
+
+// formatter ソース中で定義されたマクロ名 (マクロの呼び出しを命令の欄に置く)
+type formatter struct {
+	macros map[string]bool
+}
+
+// line 改行を含まない1行
+func (f *formatter) line(line []token.Token) string {
+	var label, opcode, comment string
+	var operands []string
+	indented := false //コメントより前に空白がある
+	space := false    //直前の Token が空白
+	for i, tok := range line {
+		switch tok.Type {
+		case token.ILLEGAL:
+			return strings.TrimRight(source(line), " \t\r")
+		case token.WHITESPACE:
+			space = true
+			if label == "" && opcode == "" {
+				indented = true
+			}
+			continue
+		case token.COMMENT:
+			comment = tok.Literal
+		case token.LABEL:
+			if opcode == "" && len(operands) == 0 {
+				// `NAME 命令` の NAME はラベル、それ以外の定義済みのマクロ名は呼び出し
+				if f.macros[tok.Literal] && (label != "" || !f.isInstruction(next(line, i))) {
+					opcode = tok.Literal
+					break
+				}
+				if label == "" {
+					label = tok.Literal
+					break
+				}
+			}
+			operands = operand(operands, tok.Literal, space)
+		case token.COMMA:
+			operands = append(operands, ",")
+		default:
+			if label == "" && opcode == "" && next(line, i).Type == token.MACRO {
+				// IN・OUT などのマクロの再定義
+				label = tok.Literal
+				break
+			}
+			if opcode == "" && len(operands) == 0 && f.isInstruction(tok) {
+				opcode = tok.Literal
+				break
+			}
+			operands = operand(operands, literal(tok), space)
+		}
+		space = false
+	}
+	var b strings.Builder
+	b.WriteString(label)
+	if opcode != "" {
+		pad(&b, OpcodeColumn)
+		b.WriteString(opcode)
+	}
+	if len(operands) > 0 {
+		pad(&b, OperandColumn)
+		b.WriteString(strings.Join(operands, ""))
+	}
+	if comment != "" {
+		switch {
+		case b.Len() > 0:
+			pad(&b, CommentColumn)
+		case indented:
+			// 行全体のコメントは命令の桁にそろえる
+			pad(&b, OpcodeColumn)
+		}
+		b.WriteString(comment)
+	}
+	return b.String()
+}
+
+// isInstruction 命令・擬似命令・マクロ名
+func (f *formatter) isInstruction(tok token.Token) bool {
+	if tok.Type == token.LABEL {
+		return f.macros[tok.Literal]
+	}
+	return tok.Type != token.REGISTER && token.LookupInst(tok.Literal) == tok.Type
+}
+
+// next line[i] の次の空白でない Token
+func next(line []token.Token, i int) token.Token {
+	for _, tok := range line[i+1:] {
+		if tok.Type != token.WHITESPACE {
+			return tok
+		}
+	}
+	return token.Token{Type: token.EOF}
+}
+
+// operand カンマで区切られていない Token は空白1つで区切る
+func operand(operands []string, s string, space bool) []string {
+	if n := len(operands); n > 0 && operands[n-1] != "," && space {
+		operands = append(operands, " ")
+	}
+	return append(operands, s)
+}
+
+// literal 10進数は先頭の 0 を除き、4桁の16進数は大文字にする
+// アセンブルした結果が変わらない書き換えだけで、誤った数値は書かれたまま
+func literal(tok token.Token) string {
+	s := tok.Literal
+	switch tok.Type {
+	case token.INT, token.EQINT:
+		prefix := ""
+		for len(s) > 0 && (s[0] == '=' || s[0] == '-') {
+			prefix, s = prefix+s[:1], s[1:]
+		}
+		s = strings.TrimLeft(s, "0")
+		if s == "" {
+			s = "0"
+		}
+		return prefix + s
+	case token.HEX, token.EQHEX:
+		i := strings.IndexByte(s, '#') + 1
+		if len(s)-i != 4 {
+			return s
+		}
+		return s[:i] + strings.ToUpper(s[i:])
+	}
+	return s
+}
+
+// pad column 桁まで空白で埋める (少なくとも空白1つ)
+func pad(b *strings.Builder, column int) {
+	n := column - b.Len()
+	if n < 1 {
+		n = 1
+	}
+	b.WriteString(strings.Repeat(" ", n))
+}
+
+func source(line []token.Token) string {
+	var b strings.Builder
+	for _, tok := range line {
+		b.WriteString(tok.Literal)
+	}
+	return b.String()
+}
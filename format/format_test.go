@@ -0,0 +1,102 @@
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"columns", "MAIN START\n LD GR1,X ;load\nX DS 1\n END", "MAIN      START\n          LD      GR1,X               ;load\nX         DS      1\n          END"},
+		{"long label", "LONGLABEL1\tLD\tGR1 , GR2", "LONGLABEL1 LD     GR1,GR2"},
+		{"long operand", "\tDC\t'ABCDEFGHIJKLMNOPQRSTU';c", "          DC      'ABCDEFGHIJKLMNOPQRSTU' ;c"},
+		{"comment line", ";top\n\t; indented\n\n", ";top\n          ; indented\n\n"},
+		// 4桁でない16進数は書かれたまま
+		{"literal", "\tLD\tGR1,=0010\n\tLD\tGR1,=#a\n\tDC\t#00ff,#ff,-007", "          LD      GR1,=10\n          LD      GR1,=#a\n          DC      #00FF,#ff,-7"},
+		{"string", "\tDC\t'a;b' ; c", "          DC      'a;b'               ; c"},
+		{"crlf", "\tRET\r\n\tEND\r\n", "          RET\n          END\n"},
+		{"illegal", "  ld gr1,x   \t", "  ld gr1,x"},
+		{"macro", "\tWAIT\nWAIT\tMACRO\t&N\nL\tSUBA\t&N,=1\n\tMEND\nL\tWAIT\tGR1", "          WAIT\nWAIT      MACRO   &N\nL         SUBA    &N,=1\n          MEND\nL         WAIT    GR1"},
+		{"redefine", "OUT\tMACRO\t&A,&B\n\tMEND\n\tOUT\tX,Y", "OUT       MACRO   &A,&B\n          MEND\n          OUT     X,Y"},
+		{"expression", "\tLD\tGR1,X + 1", "          LD      GR1,X + 1"},
+	}
+	for _, tt := range tests {
+		if got := Source(tt.src); got != tt.want {
+			t.Errorf("%s : %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+const program = `; 掛け算
+MAIN	START
+	LD	GR1,=3	;a
+	LAD	GR2,#0004
+LOOP	ADDA	GR0,GR1
+	SUBA	GR2,=1
+	JNZ	LOOP
+	OUT	MSG,LEN
+	RET
+MSG	DC	'A''B'
+LEN	DC	3
+	END
+`
+
+// assemble src の機械語と "行:コード" のエラー
+func assemble(src string) ([]uint16, []string) {
+	p := parser.New(lexer.New(src))
+	code, _ := p.Assemble()
+	var words []uint16
+	for _, op := range code {
+		words = append(words, op.Words()...)
+	}
+	var errs []string
+	for _, e := range p.Errors() {
+		errs = append(errs, fmt.Sprintf("%d:%s", e.Line, e.Code))
+	}
+	return words, errs
+}
+
+// TestIdempotent 整形しても機械語は変わらず、2回目の整形では変わらない
+func TestIdempotent(t *testing.T) {
+	formatted := Source(program)
+	if again := Source(formatted); again != formatted {
+		t.Errorf("%q, want %q", again, formatted)
+	}
+	got, errs := assemble(formatted)
+	if want, _ := assemble(program); errs != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("%04X %v, want %04X", got, errs, want)
+	}
+	if lines := strings.Split(formatted, "\n"); len(lines) != strings.Count(program, "\n")+1 {
+		t.Errorf("%d lines", len(lines))
+	}
+}
+
+// TestAssemble 整形してからアセンブルしても、機械語もエラーも変わらない
+func TestAssemble(t *testing.T) {
+	tests := []string{
+		"\tLAD\tGR1,010\n\tLD\tGR2,=007\n\tDC\t-0010,00\n\tDS\t02",
+		"\tLAD\tGR1,#00ff\n\tLD\tGR2,=#abcd",
+		"\tLAD\tGR1,#ff",
+		"\tLD\tGR1,=#a",
+		"\tDC\t#0FFFF",
+		"\tDC\t070000",
+		"N\tEQU\t010\n\tLAD\tGR1,N+01",
+	}
+	for _, src := range tests {
+		src = "MAIN\tSTART\n" + src + "\n\tRET\n\tEND\n"
+		words, errs := assemble(src)
+		got, gotErrs := assemble(Source(src))
+		if !reflect.DeepEqual(got, words) || !reflect.DeepEqual(gotErrs, errs) {
+			t.Errorf("%q : %04X %v, want %04X %v", Source(src), got, gotErrs, words, errs)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package lexer
 import (
 	"strings"
 
-	"github.com/DJSIer/GCASL2/token"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
 )
 
 // Lexer CASL2Lexer
@@ -13,15 +13,31 @@ type Lexer struct {
 	readPosition int
 	ch           byte
 	line         int
+	lineStart    int      //line 行目の先頭の位置
+	lines        []string //source lines
 }
 
 // New CASL2Lexer init
 func New(input string) *Lexer {
 	l := &Lexer{input: input, line: 1}
+	l.lines = strings.Split(strings.Replace(input, "\r\n", "\n", -1), "\n")
 	l.readChar()
 	return l
 }
 
+// Line n 行目のソース (コメントを含む)
+func (l *Lexer) Line(n int) string {
+	if n < 1 || n > len(l.lines) {
+		return ""
+	}
+	return l.lines[n-1]
+}
+
+// LineCount ソースの行数
+func (l *Lexer) LineCount() int {
+	return len(l.lines)
+}
+
 func (l *Lexer) readChar() {
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
@@ -33,9 +49,24 @@ func (l *Lexer) readChar() {
 }
 
 // NextToken Token
+// Column・EndColumn は行頭を 1 とするバイト位置 (EndColumn は Token の次の文字)
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
 	l.skipWhitespace()
+	for l.ch == ';' {
+		for l.ch != '\n' && l.ch != '\r' && l.ch != 0 {
+			l.readChar()
+		}
+		l.skipWhitespace()
+	}
+	start := l.position
+	tok := l.readToken()
+	tok.Column = start - l.lineStart + 1
+	tok.EndColumn = l.position - l.lineStart + 1
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 	switch l.ch {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
@@ -47,6 +78,17 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Line = l.line
 			return tok
 		}
+		tok = newToken(token.MINUS, l.ch)
+	case '+':
+		tok = newToken(token.PLUS, l.ch)
+	case '*':
+		tok = newToken(token.ASTERISK, l.ch)
+	case '/':
+		tok = newToken(token.SLASH, l.ch)
+	case '(':
+		tok = newToken(token.LPAREN, l.ch)
+	case ')':
+		tok = newToken(token.RPAREN, l.ch)
 	case '#':
 		if isHex(l.peekChar()) {
 			l.readChar()
@@ -55,6 +97,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Line = l.line
 			return tok
 		}
+		tok = newToken(token.ILLEGAL, l.ch)
 	case '=':
 		if isDegit(l.peekChar()) {
 			l.readChar()
@@ -71,25 +114,36 @@ func (l *Lexer) NextToken() token.Token {
 				tok.Line = l.line
 				return tok
 			}
+			tok = token.Token{Type: token.ILLEGAL, Literal: "=#"}
+			break
 		} else if l.peekChar() == '\'' {
 			l.readChar()
 			l.readChar()
-			tok.Literal = "'=" + l.readCaslLetter()
+			// リテラルの重複判定・ラベル表示のため '' はそのまま残す
+			position := l.position
+			l.readCaslLetter()
+			tok.Literal = "='" + l.input[position:l.position]
 			tok.Type = token.EQSTRING
 			tok.Line = l.line
 			return tok
 		}
+		tok = newToken(token.ILLEGAL, l.ch)
+	case '&':
+		// マクロの引数 &NAME
+		if isLetter(l.peekChar()) {
+			l.readChar()
+			tok.Literal = "&" + l.readInst()
+			tok.Type = token.PARAM
+			tok.Line = l.line
+			return tok
+		}
+		tok = newToken(token.ILLEGAL, l.ch)
 	case '\'':
 		l.readChar()
 		tok.Literal = "'" + l.readCaslLetter()
 		tok.Type = token.STRING
 		tok.Line = l.line
 		return tok
-	case ';':
-		for l.ch != '\t' && l.ch != '\n' && l.ch != '\r' {
-			l.readChar()
-		}
-		return l.NextToken()
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -118,14 +172,64 @@ func (l *Lexer) NextToken() token.Token {
 	tok.Line = l.line
 	return tok
 }
-func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+
+// NextRawToken 空白・改行・コメントも含めた Token。Literal はソースのままの文字列で、
+// 全 Token の Literal をつなげると元のソースになる
+func (l *Lexer) NextRawToken() token.Token {
+	position, line := l.position, l.line
+	column := position - l.lineStart + 1
+	var tok token.Token
+	switch l.ch {
+	case 0:
+		return token.Token{Type: token.EOF, Line: line, Column: column, EndColumn: column}
+	case ' ', '\t':
+		for l.ch == ' ' || l.ch == '\t' {
+			l.readChar()
+		}
+		tok.Type = token.WHITESPACE
+	case '\r':
+		l.readChar()
+		tok.Type = token.WHITESPACE
 		if l.ch == '\n' {
-			l.line++
+			l.readChar()
+			l.newLine()
+			tok.Type = token.NEWLINE
+		}
+	case '\n':
+		l.readChar()
+		l.newLine()
+		tok.Type = token.NEWLINE
+	case ';':
+		for l.ch != '\n' && l.ch != '\r' && l.ch != 0 {
+			l.readChar()
+		}
+		tok.Type = token.COMMENT
+	default:
+		tok = l.NextToken()
+		if tok.Type == "" {
+			tok.Type = token.ILLEGAL
 		}
+	}
+	tok.Literal = l.input[position:l.position]
+	tok.Line = line
+	tok.Column, tok.EndColumn = column, column+len(tok.Literal)
+	return tok
+}
+func (l *Lexer) skipWhitespace() {
+	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+		newLine := l.ch == '\n'
 		l.readChar()
+		if newLine {
+			l.newLine()
+		}
 	}
 }
+
+// newLine '\n' を読んだ直後に呼ぶ
+func (l *Lexer) newLine() {
+	l.line++
+	l.lineStart = l.position
+}
 func (l *Lexer) readInst() string {
 	position := l.position
 	for isLetterDegit(l.ch) {
@@ -0,0 +1,96 @@
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+)
+
+// span Token の種類・文字列と範囲
+type span struct {
+	typ    token.TokenType
+	lit    string
+	line   int
+	column int
+	end    int
+}
+
+func TestNextToken(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []span
+	}{
+		{"instruction", "LOOP\tLD\tGR1,BUF+1", []span{
+			{token.LABEL, "LOOP", 1, 1, 5}, {token.LD, "LD", 1, 6, 8}, {token.REGISTER, "GR1", 1, 9, 12},
+			{token.COMMA, ",", 1, 12, 13}, {token.LABEL, "BUF", 1, 13, 16}, {token.PLUS, "+", 1, 16, 17}, {token.INT, "1", 1, 17, 18},
+		}},
+		{"lines", "\tRET ; c\r\n  END\n", []span{{token.RET, "RET", 1, 2, 5}, {token.END, "END", 2, 3, 6}}},
+		// = や & だけの Token は ILLEGAL
+		{"numbers", "-12 #00FF =3 =#0A =-1 =#G &1", []span{
+			{token.INT, "-12", 1, 1, 4}, {token.HEX, "#00FF", 1, 5, 10}, {token.EQINT, "=3", 1, 11, 13}, {token.EQHEX, "=#0A", 1, 14, 18},
+			{token.ILLEGAL, "=", 1, 19, 20}, {token.INT, "-1", 1, 20, 22}, {token.ILLEGAL, "=#", 1, 23, 25}, {token.LABEL, "G", 1, 25, 26},
+			{token.ILLEGAL, "&", 1, 27, 28}, {token.INT, "1", 1, 28, 29},
+		}},
+		// 文字列の '' は1文字だが、範囲はソースの文字数
+		{"string", "\tDC\t'A''B',='C'", []span{{token.DC, "DC", 1, 2, 4}, {token.STRING, "'A'B'", 1, 5, 11}, {token.COMMA, ",", 1, 11, 12}, {token.EQSTRING, "='C'", 1, 12, 16}}},
+		{"param", "\tLD\t&R,&V", []span{{token.LD, "LD", 1, 2, 4}, {token.PARAM, "&R", 1, 5, 7}, {token.COMMA, ",", 1, 7, 8}, {token.PARAM, "&V", 1, 8, 10}}},
+		{"illegal", "ld #GG", []span{{token.ILLEGAL, "ld", 1, 1, 3}, {token.ILLEGAL, "#", 1, 4, 5}, {token.LABEL, "GG", 1, 5, 7}}},
+	}
+	for _, tt := range tests {
+		l := New(tt.src)
+		var got []span
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			got = append(got, span{tok.Type, tok.Literal, tok.Line, tok.Column, tok.EndColumn})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNextRawToken(t *testing.T) {
+	src := "L\tLD\tGR1,X ; c\r\n\n\tDC\t'A''B'"
+	l := New(src)
+	var b strings.Builder
+	var got []span
+	for {
+		tok := l.NextRawToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		b.WriteString(tok.Literal)
+		got = append(got, span{tok.Type, tok.Literal, tok.Line, tok.Column, tok.EndColumn})
+	}
+	// 全 Token をつなげると元のソース
+	if b.String() != src {
+		t.Errorf("%q, want %q", b.String(), src)
+	}
+	want := []span{
+		{token.LABEL, "L", 1, 1, 2}, {token.WHITESPACE, "\t", 1, 2, 3}, {token.LD, "LD", 1, 3, 5}, {token.WHITESPACE, "\t", 1, 5, 6},
+		{token.REGISTER, "GR1", 1, 6, 9}, {token.COMMA, ",", 1, 9, 10}, {token.LABEL, "X", 1, 10, 11}, {token.WHITESPACE, " ", 1, 11, 12},
+		{token.COMMENT, "; c", 1, 12, 15}, {token.NEWLINE, "\r\n", 1, 15, 17}, {token.NEWLINE, "\n", 2, 1, 2},
+		{token.WHITESPACE, "\t", 3, 1, 2}, {token.DC, "DC", 3, 2, 4}, {token.WHITESPACE, "\t", 3, 4, 5}, {token.STRING, "'A''B'", 3, 5, 11},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%v, want %v", got, want)
+	}
+}
+
+func TestLine(t *testing.T) {
+	l := New("\tRET\r\n\tEND ; c\n")
+	tests := []struct {
+		n    int
+		want string
+	}{{0, ""}, {1, "\tRET"}, {2, "\tEND ; c"}, {3, ""}, {4, ""}}
+	for _, tt := range tests {
+		if got := l.Line(tt.n); got != tt.want {
+			t.Errorf("%d : %q, want %q", tt.n, got, tt.want)
+		}
+	}
+	if n := l.LineCount(); n != 3 {
+		t.Errorf("LineCount %d, want 3", n)
+	}
+}
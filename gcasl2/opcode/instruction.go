@@ -0,0 +1,115 @@
+package opcode
+
+// Format operand format
+type Format int
+
+// operand format
+const (
+	FormatNone  Format = iota // NOP, RET
+	FormatR                   // POP r
+	FormatR1R2                // LD r1,r2
+	FormatRAdrX               // LD r,adr[,x]
+	FormatAdrX                // JUMP adr[,x]
+)
+
+// Effect 命令が読み書きするもの (COMET II の実行・トレースで使う)
+type Effect int
+
+// effect
+const (
+	ReadsMemory    Effect = 1 << iota // 実効アドレスの内容を読み出す
+	WritesRegister                    // r (r1) に書き込む
+)
+
+// Instruction 機械語命令の命令コード・オペランド形式
+type Instruction struct {
+	Mnemonic string
+	Code     uint8
+	Format   Format
+	Effect   Effect
+}
+
+// Length 命令語の長さ
+func (i Instruction) Length() int {
+	if i.Format == FormatRAdrX || i.Format == FormatAdrX {
+		return 2
+	}
+	return 1
+}
+
+// Has 命令が e を持つか
+func (i Instruction) Has(e Effect) bool {
+	return i.Effect&e != 0
+}
+
+// Syntax オペランドの書式 (LD r,adr[,x])
+func (i Instruction) Syntax() string {
+	switch i.Format {
+	case FormatR:
+		return i.Mnemonic + " r"
+	case FormatR1R2:
+		return i.Mnemonic + " r1,r2"
+	case FormatRAdrX:
+		return i.Mnemonic + " r,adr[,x]"
+	case FormatAdrX:
+		return i.Mnemonic + " adr[,x]"
+	}
+	return i.Mnemonic
+}
+
+// Instructions parser の *Statment が出力する機械語命令の一覧
+// 逆アセンブラ・COMET II シミュレータ・LSP はこの表を使う
+var Instructions = []Instruction{
+	{"NOP", 0x00, FormatNone, 0},
+	{"LD", 0x10, FormatRAdrX, ReadsMemory | WritesRegister},
+	{"ST", 0x11, FormatRAdrX, 0},
+	{"LAD", 0x12, FormatRAdrX, WritesRegister},
+	{"LD", 0x14, FormatR1R2, WritesRegister},
+	{"ADDA", 0x20, FormatRAdrX, ReadsMemory | WritesRegister},
+	{"SUBA", 0x21, FormatRAdrX, ReadsMemory | WritesRegister},
+	{"ADDL", 0x22, FormatRAdrX, ReadsMemory | WritesRegister},
+	{"SUBL", 0x23, FormatRAdrX, ReadsMemory | WritesRegister},
+	{"ADDA", 0x24, FormatR1R2, WritesRegister},
+	{"SUBA", 0x25, FormatR1R2, WritesRegister},
+	{"ADDL", 0x26, FormatR1R2, WritesRegister},
+	{"SUBL", 0x27, FormatR1R2, WritesRegister},
+	{"AND", 0x30, FormatRAdrX, ReadsMemory | WritesRegister},
+	{"OR", 0x31, FormatRAdrX, ReadsMemory | WritesRegister},
+	{"XOR", 0x32, FormatRAdrX, ReadsMemory | WritesRegister},
+	{"AND", 0x34, FormatR1R2, WritesRegister},
+	{"OR", 0x35, FormatR1R2, WritesRegister},
+	{"XOR", 0x36, FormatR1R2, WritesRegister},
+	{"CPA", 0x40, FormatRAdrX, ReadsMemory},
+	{"CPL", 0x41, FormatRAdrX, ReadsMemory},
+	{"CPA", 0x44, FormatR1R2, 0},
+	{"CPL", 0x45, FormatR1R2, 0},
+	{"SLA", 0x50, FormatRAdrX, WritesRegister},
+	{"SRA", 0x51, FormatRAdrX, WritesRegister},
+	{"SLL", 0x52, FormatRAdrX, WritesRegister},
+	{"SRL", 0x53, FormatRAdrX, WritesRegister},
+	{"JMI", 0x61, FormatAdrX, 0},
+	{"JNZ", 0x62, FormatAdrX, 0},
+	{"JZE", 0x63, FormatAdrX, 0},
+	{"JUMP", 0x64, FormatAdrX, 0},
+	{"JPL", 0x65, FormatAdrX, 0},
+	{"JOV", 0x66, FormatAdrX, 0},
+	{"PUSH", 0x70, FormatAdrX, 0},
+	{"POP", 0x71, FormatR, WritesRegister},
+	{"CALL", 0x80, FormatAdrX, 0},
+	{"RET", 0x81, FormatNone, 0},
+	{"SVC", 0xF0, FormatAdrX, 0},
+}
+
+var byCode = map[uint8]Instruction{}
+
+func init() {
+	for _, inst := range Instructions {
+		byCode[inst.Code] = inst
+	}
+}
+
+// Lookup 命令コードから命令を引く
+func Lookup(code uint8) (Instruction, bool) {
+	inst, ok := byCode[code]
+	return inst, ok
+}
@@ -0,0 +1,45 @@
+package opcode
+
+import (
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+)
+
+// Opcode CASL2 Opcode struct
+type Opcode struct {
+	Code      uint16         //2byte
+	Addr      uint16         //Address
+	AddrLabel string         //Address Label
+	AddrToken token.Token    `json:"-"` //AddrLabel の Token (エラーの位置)
+	Op        uint8          //1byte
+	Length    int            //Opcode Length
+	Label     *symbol.Symbol `json:"Label,omitempty"` //Label
+	Token     token.Token    //token
+	Scope     int            `json:"-"` //プログラム (START〜END) の番号
+}
+
+func New() *Opcode {
+	o := &Opcode{
+		Addr: 0xFFFF,
+	}
+	return o
+}
+
+// Words メモリに配置される語の列
+// 命令は Code (2語命令は Code, Addr)、DC・DS などの擬似命令は Addr を先頭に Length 語
+func (o *Opcode) Words() []uint16 {
+	if o.Length <= 0 {
+		return nil
+	}
+	words := make([]uint16, o.Length)
+	if o.Op == 0x00 {
+		words[0] = o.Addr
+		return words
+	}
+	words[0] = o.Code
+	if o.Length > 1 {
+		words[1] = o.Addr
+	}
+	return words
+}
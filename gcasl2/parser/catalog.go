@@ -0,0 +1,215 @@
+package parser
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Entry メッセージカタログの1項目
+// Message の {1}・{2} は ParserError.Args に置き換える
+type Entry struct {
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	Explanation string `json:"explanation"`
+	Fix         string `json:"fix"`
+}
+
+// DefaultLanguage 言語を指定しないときの言語 (ParserError.Message はこの言語)
+const DefaultLanguage = "ja"
+
+// Languages カタログのある言語
+var Languages = []string{"ja", "en"}
+
+var catalog = map[string]map[string]Entry{
+	"ja": {
+		CodeDuplicateLabel:  {Message: "重複定義エラー Label : \"{1}\"", Explanation: "同じプログラム (START〜END) の中で、同じ名前のラベルまたは定数が2回以上定義されています。", Fix: "どちらかのラベルの名前を変えてください。START のラベルはソース全体で1つだけです。"},
+		CodeUndefined:       {Message: "\"{1}\"は解決できません", Explanation: "オペランドのラベルが、このプログラムにも他のプログラムの START ラベルにも見つかりません。", Fix: "ラベルの綴り (大文字) と定義を確認してください。別のソースのラベルは gcasl-link で結合します。"},
+		CodeUnknown:         {Message: "\"{1}\" : 解決できません\n", Explanation: "命令の欄に、命令・擬似命令・マクロではない語があります。", Fix: "命令の綴りを確認してください。ラベルは行の先頭 (1文字目) から、命令は空白のあとに書きます。"},
+		CodeSyntax:          {Message: "\"{1}\" : コンパイルエラー", Explanation: "行を命令として解析できませんでした。", Fix: "命令とオペランドの書き方を確認してください。"},
+		CodeMissingEnd:      {Message: "前のプログラムにENDがありません。対象 : \"{1}\"", Explanation: "前のプログラムを END で終える前に、次の START があります。", Fix: "前のプログラムの終わりに END を書いてください。"},
+		CodeStartLabel:      {Message: "STARTにラベルがありません。対象 : \"{1}\"", Explanation: "START にはプログラム名になるラベルが必要です。", Fix: "「MAIN START」のように START の前にラベルを書いてください。"},
+		CodeEndWithoutStart: {Message: "ENDに対応するSTARTがありません。対象 : \"{1}\"", Explanation: "END の前に、対応する START がありません。", Fix: "プログラムの先頭に START を書くか、余分な END を削除してください。"},
+		CodeNumberOrLabel:   {Message: "数値・ラベルではありません。対象 : \"{1}\"\n", Explanation: "このオペランドには数値 (10進・#16進)・ラベル・リテラルが必要です。", Fix: "オペランドを数値またはラベルにしてください。"},
+		CodeInvalidNumber:   {Message: "数値が適正ではありません。対象 : \"{1}\"\n", Explanation: "数値が 1語 (16ビット) に収まらないか、数値として読めません。", Fix: "-32768〜65535 の範囲の10進数、または #0000〜#FFFF の16進数にしてください。"},
+		CodeRegister:        {Message: "{2} \"{1}\" \n\"{1}\"はレジスタではありません。", Explanation: "このオペランドには汎用レジスタ GR0〜GR7 が必要です。", Fix: "GR0〜GR7 のいずれかを書いてください。指標レジスタ (x) に GR0 は使えません。"},
+		CodeComma:           {Message: "{2} {1} の後にカンマがありません。", Explanation: "オペランドの区切りのカンマがありません。", Fix: "「LD GR1,BUF」のようにオペランドをカンマで区切ってください。"},
+		CodeOperand:         {Message: "数値・レジスタ・ラベルではありません。対象 : \"{1}\"\n", Explanation: "このオペランドには数値・レジスタ・ラベルのいずれかが必要です。", Fix: "オペランドを GR0〜GR7、数値、ラベルのいずれかにしてください。"},
+		CodeNumber:          {Message: "数値でなければいけません。対象 : \"{1}\"", Explanation: "DS の語数には数値または定義済みの定数が必要です。", Fix: "「DS 10」のように語数を書いてください。"},
+		CodeHex:             {Message: "16進数数値が適正ではありません。\n#0000~#FFFFまで使用できます対象 : \"{1}\"", Explanation: "16進数は # のあとに 4桁で書きます。", Fix: "#000A のように 4桁にしてください。"},
+		CodeEmptyString:     {Message: "\"{1}\" : 文字定数が空です\n", Explanation: "文字定数のリテラル ='...' に文字がありません。", Fix: "='A' のように 1文字以上書いてください。"},
+		CodeEquLabel:        {Message: "EQUにラベルがありません。対象 : \"{1}\"", Explanation: "EQU は「名前 EQU 値」の形で定数を定義します。", Fix: "EQU の前に定数の名前を書いてください。"},
+		CodeConstant:        {Message: "数値・定数ではありません。対象 : \"{1}\"", Explanation: "ここには数値・定義済みの定数、またはそれらの式が必要です。番地のラベルは使えません。", Fix: "数値または EQU で定義した定数にしてください。"},
+		CodeDirectiveLabel:  {Message: "{1}にラベルは付けられません。対象 : \"{2}\"", Explanation: "IF・ELSE・ENDIF・INCLUDE は番地を持たないため、ラベルを付けられません。", Fix: "ラベルを次の命令の行に移してください。"},
+		CodeElse:            {Message: "ELSEに対応するIFがありません。対象 : \"{1}\"", Explanation: "ELSE の前に対応する IF がないか、すでに ELSE があります。", Fix: "IF 値 〜 ELSE 〜 ENDIF の形にしてください。"},
+		CodeEndif:           {Message: "ENDIFに対応するIFがありません。対象 : \"{1}\"", Explanation: "ENDIF の前に対応する IF がありません。", Fix: "余分な ENDIF を削除するか、IF を書いてください。"},
+		CodeUnclosedIf:      {Message: "IFに対応するENDIFがありません。", Explanation: "ソースの終わりまでに IF が ENDIF で閉じられていません。", Fix: "条件アセンブルする部分の終わりに ENDIF を書いてください。"},
+		CodeConstantName:    {Message: "\"{1}\" : 定数名が適正ではありません。", Explanation: "定数名は英大文字で始まる英大文字・数字で、命令名・レジスタ名は使えません。", Fix: "define の NAME を変えてください。"},
+		CodeConstantValue:   {Message: "\"{1}\" : 定数の値は10進数または#16進数でなければいけません。", Explanation: "define の VALUE が数値として読めません。", Fix: "NAME=10 や NAME=#000A の形にしてください。"},
+		CodeNotConstant:     {Message: "\"{1}\" : 定義済みの定数ではありません。", Explanation: "EQU・DS・IF の値には、その行より前に EQU で定義した定数しか使えません。", Fix: "定数を使う行より前で EQU を定義してください。"},
+		CodeMissingValue:    {Message: "{1} \n{1}のあとは数値・定数でなければいけません。", Explanation: "EQU・IF の値がありません。", Fix: "「SIZE EQU 10」「IF DEBUG」のように値を書いてください。"},
+		CodeMend:            {Message: "MENDに対応するMACROがありません。対象 : \"{1}\"", Explanation: "MEND の前に対応する MACRO がありません。", Fix: "余分な MEND を削除するか、マクロ定義の先頭に「名前 MACRO」を書いてください。"},
+		CodeMacroName:       {Message: "MACROにマクロ名がありません。対象 : \"{1}\"", Explanation: "MACRO は「名前 MACRO &引数」の形で定義します。", Fix: "MACRO の前にマクロ名を書いてください。"},
+		CodeMacroReserved:   {Message: "\"{1}\" : 命令名はマクロ名に使えません", Explanation: "機械語命令・擬似命令の名前はマクロ名にできません (IN・OUT・RPUSH・RPOP は再定義できます)。", Fix: "別のマクロ名にしてください。"},
+		CodeParamComma:      {Message: "MACRO \"{1}\" \nマクロの引数はカンマで区切ってください。", Explanation: "MACRO の引数の間にカンマがありません。", Fix: "「NAME MACRO &A,&B」のように書いてください。"},
+		CodeParamName:       {Message: "MACRO \"{1}\" \nマクロの引数は&で始まる名前でなければいけません。", Explanation: "MACRO の引数は & で始まる名前です。", Fix: "&A のように & を付けてください。"},
+		CodeDuplicateParam:  {Message: "重複定義エラー Param : \"{1}\"", Explanation: "同じ名前の引数が2回以上あります。", Fix: "引数の名前を変えてください。"},
+		CodeUnclosedMacro:   {Message: "\"{1}\" : MENDがありません。", Explanation: "ソースの終わりまでにマクロ定義が MEND で閉じられていません。", Fix: "マクロ本体の終わりに MEND を書いてください。"},
+		CodeNotParam:        {Message: "\"{1}\" : マクロ {2} の引数ではありません。", Explanation: "マクロ本体で、MACRO の行にない &NAME を使っています。", Fix: "MACRO の行に引数を追加するか、綴りを直してください。"},
+		CodeMacroDepth:      {Message: "\"{1}\" : マクロの展開が深すぎます。再帰していないか確認してください。", Explanation: "マクロの中からマクロを呼び出す入れ子が深すぎます。", Fix: "マクロが自分自身を呼び出していないか確認してください。"},
+		CodeMacroArgs:       {Message: "{1} \nマクロの引数が多すぎます。{2}個まで指定できます。", Explanation: "呼び出しの引数が、MACRO で定義した引数より多くあります。", Fix: "余分な引数を削除してください。"},
+		CodeIncludeOperand:  {Message: "INCLUDE \nINCLUDEのあとは'ファイル名'でなければいけません。", Explanation: "INCLUDE は「INCLUDE 'ファイル名'」の形で書きます。", Fix: "ファイル名を ' で囲んでください。"},
+		CodeIncludeCycle:    {Message: "\"{1}\" : INCLUDEが循環しています。", Explanation: "INCLUDE したファイルが、自分自身またはそれを INCLUDE しているファイルを INCLUDE しています。", Fix: "共通部分を別のファイルに分けて、循環しないようにしてください。"},
+		CodeIncludeDisabled: {Message: "\"{1}\" : INCLUDEは使用できません。", Explanation: "この実行環境では INCLUDE するファイルの置き場所が設定されていません。", Fix: "ファイルの内容をソースに直接書いてください。"},
+		CodeIncludeSource:   {Message: "INCLUDEできません。{2}", Explanation: "INCLUDE するファイルが見つからないか、検索パスの外にあります。", Fix: "ファイル名と検索パス (-I・GCASL_LIBRARY) を確認してください。.. や絶対パスは使えません。"},
+		CodeExpr:            {Message: "\"{1}\" : 式が適正ではありません。", Explanation: "式は数値・ラベル・定数を + - * / と括弧でつないだ形です。", Fix: "演算子の前後と括弧の対応を確認してください。"},
+		CodeExprProduct:     {Message: "\"{1}\" : 番地のラベルは掛け算・割り算に使えません。", Explanation: "番地は再配置されるため、掛け算・割り算の結果は決まりません。", Fix: "掛け算・割り算には EQU の定数か、番地-番地 の差を使ってください。"},
+		CodeExprDivide:      {Message: "\"{1}\" : 0で割ることはできません。", Explanation: "式の割り算の右辺が 0 です。", Fix: "割る数を確認してください。"},
+		CodeExprAddress:     {Message: "\"{1}\" : 番地の式が適正ではありません。番地+定数・番地-番地の形にしてください。", Explanation: "番地を含む式の結果は、番地1つ分か定数でなければいけません (番地+番地 などは不可)。", Fix: "番地+定数・番地-定数・番地-番地 のいずれかの形にしてください。"},
+		CodeGR0:             {Message: "\"{1}\"が使用されています", Explanation: "GR0 は指標レジスタ (x) として使えないため、アドレスの計算に使うと意図と違う結果になることがあります。", Fix: "アドレスの計算に使うレジスタなら GR1〜GR7 を使ってください。"},
+		CodeUnusedLabel:     {Message: "\"{1}\"は使用されていません", Explanation: "このラベルはどこからも参照されていません。", Fix: "不要なら削除してください。"},
+		CodeMacroRedefined:  {Message: "\"{1}\" : マクロを再定義しました", Explanation: "同じ名前のマクロ (IN・OUT などの組み込みマクロを含む) を定義し直しました。以降の呼び出しは新しい定義になります。", Fix: "意図した再定義でなければ、マクロ名を変えてください。"},
+	},
+	"en": {
+		CodeDuplicateLabel:  {Message: "Duplicate definition error Label : \"{1}\"", Explanation: "A label or constant with the same name is defined more than once in the same program (START to END).", Fix: "Rename one of the labels. START labels must be unique across the whole source."},
+		CodeUndefined:       {Message: "\"{1}\" cannot be resolved", Explanation: "The operand label is not defined in this program and is not the START label of another program.", Fix: "Check the spelling (upper case) and the definition. Labels in another source are resolved by gcasl-link."},
+		CodeUnknown:         {Message: "\"{1}\" : cannot be resolved\n", Explanation: "The instruction field contains a word that is not an instruction, pseudo instruction or macro.", Fix: "Check the spelling. Labels start in column 1; instructions come after white space."},
+		CodeSyntax:          {Message: "\"{1}\" : compile error", Explanation: "The line could not be parsed as an instruction.", Fix: "Check the instruction and its operands."},
+		CodeMissingEnd:      {Message: "The previous program has no END. At : \"{1}\"", Explanation: "A new START appears before the previous program is closed by END.", Fix: "Add END at the end of the previous program."},
+		CodeStartLabel:      {Message: "START has no label. At : \"{1}\"", Explanation: "START requires a label, which becomes the program name.", Fix: "Write a label before START, e.g. \"MAIN START\"."},
+		CodeEndWithoutStart: {Message: "END has no matching START. At : \"{1}\"", Explanation: "There is no START before this END.", Fix: "Add START at the beginning of the program or remove the extra END."},
+		CodeNumberOrLabel:   {Message: "Not a number or label. At : \"{1}\"\n", Explanation: "This operand must be a number (decimal or #hex), a label or a literal.", Fix: "Use a number or a label."},
+		CodeInvalidNumber:   {Message: "Invalid number. At : \"{1}\"\n", Explanation: "The number does not fit in one word (16 bits) or cannot be read as a number.", Fix: "Use a decimal number from -32768 to 65535 or a hex number from #0000 to #FFFF."},
+		CodeRegister:        {Message: "{2} \"{1}\" \n\"{1}\" is not a register.", Explanation: "This operand must be a general register GR0 to GR7.", Fix: "Use one of GR0 to GR7. GR0 cannot be used as an index register (x)."},
+		CodeComma:           {Message: "No comma after {2} {1}.", Explanation: "The comma separating the operands is missing.", Fix: "Separate the operands with commas, e.g. \"LD GR1,BUF\"."},
+		CodeOperand:         {Message: "Not a number, register or label. At : \"{1}\"\n", Explanation: "This operand must be a number, a register or a label.", Fix: "Use GR0 to GR7, a number or a label."},
+		CodeNumber:          {Message: "Must be a number. At : \"{1}\"", Explanation: "The word count of DS must be a number or a defined constant.", Fix: "Write the word count, e.g. \"DS 10\"."},
+		CodeHex:             {Message: "Invalid hexadecimal number.\n#0000 to #FFFF can be used. At : \"{1}\"", Explanation: "Hexadecimal numbers are written as # followed by exactly 4 digits.", Fix: "Use 4 digits, e.g. #000A."},
+		CodeEmptyString:     {Message: "\"{1}\" : the string constant is empty\n", Explanation: "The literal ='...' contains no characters.", Fix: "Write at least one character, e.g. ='A'."},
+		CodeEquLabel:        {Message: "EQU has no label. At : \"{1}\"", Explanation: "EQU defines a constant in the form \"NAME EQU value\".", Fix: "Write the constant name before EQU."},
+		CodeConstant:        {Message: "Not a number or constant. At : \"{1}\"", Explanation: "A number, a defined constant or an expression of them is required here. Address labels cannot be used.", Fix: "Use a number or a constant defined by EQU."},
+		CodeDirectiveLabel:  {Message: "{1} cannot have a label. At : \"{2}\"", Explanation: "IF, ELSE, ENDIF and INCLUDE have no address, so they cannot be labeled.", Fix: "Move the label to the next instruction line."},
+		CodeElse:            {Message: "ELSE has no matching IF. At : \"{1}\"", Explanation: "There is no IF before this ELSE, or the IF already has an ELSE.", Fix: "Use the form IF value ... ELSE ... ENDIF."},
+		CodeEndif:           {Message: "ENDIF has no matching IF. At : \"{1}\"", Explanation: "There is no IF before this ENDIF.", Fix: "Remove the extra ENDIF or add IF."},
+		CodeUnclosedIf:      {Message: "IF has no matching ENDIF.", Explanation: "The IF is not closed by ENDIF before the end of the source.", Fix: "Add ENDIF at the end of the conditional part."},
+		CodeConstantName:    {Message: "\"{1}\" : invalid constant name.", Explanation: "Constant names start with an upper-case letter and contain upper-case letters and digits. Instruction and register names cannot be used.", Fix: "Change NAME in define."},
+		CodeConstantValue:   {Message: "\"{1}\" : the constant value must be a decimal or #hex number.", Explanation: "VALUE in define cannot be read as a number.", Fix: "Use the form NAME=10 or NAME=#000A."},
+		CodeNotConstant:     {Message: "\"{1}\" : not a defined constant.", Explanation: "Values of EQU, DS and IF can only use constants defined by EQU before that line.", Fix: "Define the constant with EQU before it is used."},
+		CodeMissingValue:    {Message: "{1} \n{1} must be followed by a number or constant.", Explanation: "The value of EQU or IF is missing.", Fix: "Write the value, e.g. \"SIZE EQU 10\" or \"IF DEBUG\"."},
+		CodeMend:            {Message: "MEND has no matching MACRO. At : \"{1}\"", Explanation: "There is no MACRO before this MEND.", Fix: "Remove the extra MEND or start the definition with \"NAME MACRO\"."},
+		CodeMacroName:       {Message: "MACRO has no macro name. At : \"{1}\"", Explanation: "Macros are defined in the form \"NAME MACRO &arg\".", Fix: "Write the macro name before MACRO."},
+		CodeMacroReserved:   {Message: "\"{1}\" : instruction names cannot be used as macro names", Explanation: "Names of machine and pseudo instructions cannot be macro names (IN, OUT, RPUSH and RPOP can be redefined).", Fix: "Choose another macro name."},
+		CodeParamComma:      {Message: "MACRO \"{1}\" \nSeparate macro parameters with commas.", Explanation: "A comma is missing between the MACRO parameters.", Fix: "Write them as \"NAME MACRO &A,&B\"."},
+		CodeParamName:       {Message: "MACRO \"{1}\" \nMacro parameters must be names starting with &.", Explanation: "MACRO parameters are names starting with &.", Fix: "Add &, e.g. &A."},
+		CodeDuplicateParam:  {Message: "Duplicate definition error Param : \"{1}\"", Explanation: "The same parameter name appears more than once.", Fix: "Rename the parameter."},
+		CodeUnclosedMacro:   {Message: "\"{1}\" : no MEND.", Explanation: "The macro definition is not closed by MEND before the end of the source.", Fix: "Add MEND at the end of the macro body."},
+		CodeNotParam:        {Message: "\"{1}\" : not a parameter of macro {2}.", Explanation: "The macro body uses an &NAME that is not on the MACRO line.", Fix: "Add the parameter to the MACRO line or fix the spelling."},
+		CodeMacroDepth:      {Message: "\"{1}\" : macro expansion is too deep. Check that the macro is not recursive.", Explanation: "Macros calling macros are nested too deeply.", Fix: "Make sure the macro does not call itself."},
+		CodeMacroArgs:       {Message: "{1} \nToo many macro arguments. Up to {2} can be given.", Explanation: "The call has more arguments than the MACRO definition.", Fix: "Remove the extra arguments."},
+		CodeIncludeOperand:  {Message: "INCLUDE \nINCLUDE must be followed by 'file name'.", Explanation: "INCLUDE is written as \"INCLUDE 'file'\".", Fix: "Enclose the file name in single quotes."},
+		CodeIncludeCycle:    {Message: "\"{1}\" : INCLUDE is circular.", Explanation: "The included file includes itself or a file that includes it.", Fix: "Move the shared part into a separate file so that includes do not loop."},
+		CodeIncludeDisabled: {Message: "\"{1}\" : INCLUDE cannot be used.", Explanation: "No location for included files is configured in this environment.", Fix: "Write the contents of the file directly in the source."},
+		CodeIncludeSource:   {Message: "Cannot INCLUDE. {2}", Explanation: "The file was not found or is outside the search path.", Fix: "Check the file name and the search path (-I, GCASL_LIBRARY). \"..\" and absolute paths are not allowed."},
+		CodeExpr:            {Message: "\"{1}\" : invalid expression.", Explanation: "Expressions combine numbers, labels and constants with + - * / and parentheses.", Fix: "Check the operators and matching parentheses."},
+		CodeExprProduct:     {Message: "\"{1}\" : address labels cannot be multiplied or divided.", Explanation: "Addresses are relocated, so products and quotients of them are undefined.", Fix: "Multiply or divide EQU constants or address differences (address-address) instead."},
+		CodeExprDivide:      {Message: "\"{1}\" : division by zero.", Explanation: "The right side of a division in the expression is 0.", Fix: "Check the divisor."},
+		CodeExprAddress:     {Message: "\"{1}\" : invalid address expression. Use address+constant or address-address.", Explanation: "An expression containing addresses must result in a single address or a constant (address+address is not allowed).", Fix: "Use address+constant, address-constant or address-address."},
+		CodeGR0:             {Message: "\"{1}\" is used", Explanation: "GR0 cannot be used as an index register (x), so using it for address calculation may not work as intended.", Fix: "Use GR1 to GR7 for registers used in address calculation."},
+		CodeUnusedLabel:     {Message: "\"{1}\" is not used", Explanation: "No instruction refers to this label.", Fix: "Remove it if it is not needed."},
+		CodeMacroRedefined:  {Message: "\"{1}\" : macro redefined", Explanation: "A macro with the same name (including the built-in IN and OUT) was defined again. Later calls use the new definition.", Fix: "Rename the macro if the redefinition was not intended."},
+	},
+}
+
+// Lookup lang のカタログの code の項目 (lang にない場合は DefaultLanguage)
+func Lookup(code, lang string) (Entry, bool) {
+	e, ok := catalog[lang][code]
+	if !ok {
+		e, ok = catalog[DefaultLanguage][code]
+	}
+	e.Code = code
+	return e, ok
+}
+
+// Catalog lang のカタログ (コード順)
+func Catalog(lang string) []Entry {
+	var entries []Entry
+	for code := range catalog[DefaultLanguage] {
+		e, _ := Lookup(code, lang)
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Code < entries[j].Code })
+	return entries
+}
+
+// Format Entry.Message の {n} を args[n-1] に置き換える
+func (e Entry) Format(args []string) string {
+	msg := e.Message
+	for i, arg := range args {
+		msg = strings.Replace(msg, "{"+strconv.Itoa(i+1)+"}", arg, -1)
+	}
+	return msg
+}
+
+// MatchLanguage lang パラメータまたは Accept-Language ("en-US,en;q=0.9,ja;q=0.8") から
+// カタログのある言語を選ぶ (該当しなければ DefaultLanguage)
+func MatchLanguage(accept string) string {
+	best, quality := DefaultLanguage, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if i := strings.IndexAny(tag, "-_"); i >= 0 {
+			tag = tag[:i]
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			if f = strings.TrimSpace(f); strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if _, ok := catalog[tag]; ok && q > quality {
+			best, quality = tag, q
+		}
+	}
+	return best
+}
+
+// Localize lang のメッセージと説明・修正方法にする
+func (e ParserError) Localize(lang string) ParserError {
+	e.Message, e.Explanation, e.Fix = localize(e.Code, lang, e.Args, e.Message)
+	return e
+}
+
+// Localize ParserError.Localize と同じ
+func (w ParserWarning) Localize(lang string) ParserWarning {
+	w.Message, w.Explanation, w.Fix = localize(w.Code, lang, w.Args, w.Message)
+	return w
+}
+
+// localize コードのないメッセージ (peekError など) は msg のまま
+func localize(code, lang string, args []string, msg string) (string, string, string) {
+	e, ok := Lookup(code, lang)
+	if !ok {
+		return msg, "", ""
+	}
+	return e.Format(args), e.Explanation, e.Fix
+}
+
+// LocalizeErrors errs をそれぞれ Localize する
+func LocalizeErrors(errs []ParserError, lang string) []ParserError {
+	out := make([]ParserError, len(errs))
+	for i, e := range errs {
+		out[i] = e.Localize(lang)
+	}
+	return out
+}
+
+// LocalizeWarnings ws をそれぞれ Localize する
+func LocalizeWarnings(ws []ParserWarning, lang string) []ParserWarning {
+	out := make([]ParserWarning, len(ws))
+	for i, w := range ws {
+		out[i] = w.Localize(lang)
+	}
+	return out
+}
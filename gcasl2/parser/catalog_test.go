@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+)
+
+// codes code.go のすべてのコード
+var codes = []string{
+	CodeDuplicateLabel, CodeUndefined, CodeUnknown, CodeSyntax, CodeMissingEnd, CodeStartLabel, CodeEndWithoutStart,
+	CodeNumberOrLabel, CodeInvalidNumber, CodeRegister, CodeComma, CodeOperand, CodeNumber, CodeHex, CodeEmptyString,
+	CodeEquLabel, CodeConstant, CodeDirectiveLabel, CodeElse, CodeEndif, CodeUnclosedIf, CodeConstantName, CodeConstantValue, CodeNotConstant, CodeMissingValue,
+	CodeMend, CodeMacroName, CodeMacroReserved, CodeParamComma, CodeParamName, CodeDuplicateParam, CodeUnclosedMacro, CodeNotParam, CodeMacroDepth, CodeMacroArgs,
+	CodeIncludeOperand, CodeIncludeCycle, CodeIncludeDisabled, CodeIncludeSource,
+	CodeExpr, CodeExprProduct, CodeExprDivide, CodeExprAddress,
+	CodeGR0, CodeUnusedLabel, CodeMacroRedefined,
+}
+
+// TestCatalog すべてのコードにすべての言語のメッセージがあり、
+// 引数の {n} は DefaultLanguage のメッセージが使うものだけ
+func TestCatalog(t *testing.T) {
+	placeholder := regexp.MustCompile(`\{\d\}`)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if seen[code] {
+			t.Errorf("%s : 重複しています", code)
+		}
+		seen[code] = true
+		args := map[string]bool{}
+		for _, arg := range placeholder.FindAllString(catalog[DefaultLanguage][code].Message, -1) {
+			args[arg] = true
+		}
+		for _, lang := range Languages {
+			e, ok := catalog[lang][code]
+			if !ok || e.Message == "" || e.Explanation == "" || e.Fix == "" {
+				t.Errorf("%s : %s の項目がありません %+v", code, lang, e)
+				continue
+			}
+			for _, arg := range placeholder.FindAllString(e.Message, -1) {
+				if !args[arg] {
+					t.Errorf("%s : %s の %s は %s にありません", code, lang, arg, DefaultLanguage)
+				}
+			}
+		}
+	}
+	for _, lang := range Languages {
+		if n := len(catalog[lang]); n != len(codes) {
+			t.Errorf("%s : %d entries, want %d", lang, n, len(codes))
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		code string
+		lang string
+		want string
+		ok   bool
+	}{
+		{CodeRegister, "ja", "{2} \"{1}\" \n\"{1}\"はレジスタではありません。", true},
+		{CodeRegister, "en", "{2} \"{1}\" \n\"{1}\" is not a register.", true},
+		// カタログのない言語は DefaultLanguage
+		{CodeRegister, "fr", "{2} \"{1}\" \n\"{1}\"はレジスタではありません。", true},
+		{"E9999", "en", "", false},
+	}
+	for _, tt := range tests {
+		e, ok := Lookup(tt.code, tt.lang)
+		if e.Message != tt.want || ok != tt.ok || e.Code != tt.code {
+			t.Errorf("%s %s : %+v %v, want %q %v", tt.code, tt.lang, e, ok, tt.want, tt.ok)
+		}
+	}
+	entries := Catalog("en")
+	if len(entries) != len(codes) || !sort.SliceIsSorted(entries, func(i, j int) bool { return entries[i].Code < entries[j].Code }) {
+		t.Errorf("%d entries %v", len(entries), entries)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		msg  string
+		args []string
+		want string
+	}{
+		{"{1} : {2}", []string{"A", "B"}, "A : B"},
+		{"{1}{1}", []string{"A"}, "AA"},
+		{"{1} {2}", []string{"A"}, "A {2}"},
+		{"none", []string{"A"}, "none"},
+	}
+	for _, tt := range tests {
+		if got := (Entry{Message: tt.msg}).Format(tt.args); got != tt.want {
+			t.Errorf("%q %v : %q, want %q", tt.msg, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "ja"},
+		{"en", "en"},
+		{"en-US", "en"},
+		{"EN_gb", "en"},
+		{"fr", "ja"},
+		{"fr,en;q=0.5", "en"},
+		{"en-US,en;q=0.9,ja;q=0.8", "en"},
+		{"en;q=0.5,ja;q=0.8", "ja"},
+		{"ja;q=0,en;q=0.1", "en"},
+	}
+	for _, tt := range tests {
+		if got := MatchLanguage(tt.accept); got != tt.want {
+			t.Errorf("%q : %s, want %s", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestLocalizeErrors(t *testing.T) {
+	errs := []ParserError{
+		{Line: 2, Message: "LD \"GR9\" \n\"GR9\"はレジスタではありません。", Code: CodeRegister, Args: []string{"GR9", "LD"}},
+		{Line: 3, Message: "コードのないエラー"},
+	}
+	got := LocalizeErrors(errs, "en")
+	if got[0].Message != "LD \"GR9\" \n\"GR9\" is not a register." || got[0].Explanation == "" || got[0].Fix == "" {
+		t.Errorf("%+v", got[0])
+	}
+	if got[1].Message != "コードのないエラー" || got[1].Explanation != "" {
+		t.Errorf("%+v", got[1])
+	}
+	// 元のエラーは変えない
+	if errs[0].Message != "LD \"GR9\" \n\"GR9\"はレジスタではありません。" || errs[0].Explanation != "" {
+		t.Errorf("%+v", errs[0])
+	}
+	ws := LocalizeWarnings([]ParserWarning{{Line: 4, Code: CodeUnusedLabel, Args: []string{"Y"}}}, "ja")
+	if ws[0].Message != `"Y"は使用されていません` {
+		t.Errorf("%+v", ws[0])
+	}
+	// パーサのエラーも Args からそれぞれの言語にできる
+	_, p, _ := assemble("MAIN\tSTART\n\tLD\tGR1 X\n\tRET\nX\tDS\t1\n\tEND\n")
+	if got := LocalizeErrors(p.Errors(), "en"); len(got) != 1 || got[0].Message != "No comma after LD GR1." {
+		t.Errorf("%+v", got)
+	}
+}
+
+// TestMessage ja の Message は元のパーサと同じ文言
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"\tLD\tGR9,X", "LD \"GR9\" \n\"GR9\"はレジスタではありません。"},
+		{"\tLD\tGR1 X", "LD GR1 の後にカンマがありません。"},
+		{"\tLAD\tGR1,X,GR8", "LAD \"GR8\" \n\"GR8\"はレジスタではありません。"},
+		{"\tFOO\tGR1", "\"GR1\" : 解決できません\n"},
+		{"\tDC\t70000", "数値が適正ではありません。対象 : \"70000\"\n"},
+		{"\tJUMP\tGR1", "数値・ラベルではありません。対象 : \"GR1\"\n"},
+		{"\tLD\tGR1,Y", "\"Y\"は解決できません"},
+	}
+	for _, tt := range tests {
+		_, p, _ := assemble("MAIN\tSTART\n" + tt.src + "\n\tRET\nX\tDS\t1\n\tEND\n")
+		if errs := p.Errors(); len(errs) != 1 || errs[0].Message != tt.want {
+			t.Errorf("%q : %+v, want %q", tt.src, errs, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+)
+
+// ParserError.Code・ParserWarning.Code の値
+// 一度割り当てた番号は変えない (クライアントがドキュメントへのリンクに使う)
+const (
+	// E00xx プログラムの構成・ラベル
+	CodeDuplicateLabel  = "E0001"
+	CodeUndefined       = "E0002"
+	CodeUnknown         = "E0003"
+	CodeSyntax          = "E0004"
+	CodeMissingEnd      = "E0006"
+	CodeStartLabel      = "E0007"
+	CodeEndWithoutStart = "E0008"
+	// E0005 は欠番 (START のない命令は START ラベルのないプログラムになる)
+
+	// E01xx オペランド
+	CodeNumberOrLabel = "E0101"
+	CodeInvalidNumber = "E0102"
+	CodeRegister      = "E0103"
+	CodeComma         = "E0104"
+	CodeOperand       = "E0105"
+	CodeNumber        = "E0106"
+	CodeHex           = "E0107"
+	CodeEmptyString   = "E0108"
+
+	// E02xx EQU・IF・定数
+	CodeEquLabel       = "E0201"
+	CodeConstant       = "E0202"
+	CodeDirectiveLabel = "E0203"
+	CodeElse           = "E0204"
+	CodeEndif          = "E0205"
+	CodeUnclosedIf     = "E0206"
+	CodeConstantName   = "E0207"
+	CodeConstantValue  = "E0208"
+	CodeNotConstant    = "E0209"
+	CodeMissingValue   = "E0210"
+
+	// E03xx マクロ
+	CodeMend           = "E0301"
+	CodeMacroName      = "E0302"
+	CodeMacroReserved  = "E0303"
+	CodeParamComma     = "E0304"
+	CodeParamName      = "E0305"
+	CodeDuplicateParam = "E0306"
+	CodeUnclosedMacro  = "E0307"
+	CodeNotParam       = "E0308"
+	CodeMacroDepth     = "E0309"
+	CodeMacroArgs      = "E0310"
+
+	// E04xx INCLUDE
+	CodeIncludeOperand  = "E0401"
+	CodeIncludeCycle    = "E0402"
+	CodeIncludeDisabled = "E0403"
+	CodeIncludeSource   = "E0404"
+
+	// E05xx 式
+	CodeExpr        = "E0501"
+	CodeExprProduct = "E0502"
+	CodeExprDivide  = "E0503"
+	CodeExprAddress = "E0504"
+
+	// W 警告
+	CodeGR0            = "W0001"
+	CodeUnusedLabel    = "W0002"
+	CodeMacroRedefined = "W0301"
+)
+
+// codeError コード付きのエラー (式の評価など、呼び出し側で ParserError にするもの)
+// args はカタログのメッセージの引数 (format の引数を文字列にしたもの)
+type codeError struct {
+	code string
+	args []string
+}
+
+// Error DefaultLanguage のメッセージ
+func (e *codeError) Error() string {
+	entry, _ := Lookup(e.code, DefaultLanguage)
+	return entry.Format(e.args)
+}
+
+func errorf(code string, a ...interface{}) error {
+	args := make([]string, len(a))
+	for i, v := range a {
+		args[i] = fmt.Sprint(v)
+	}
+	return &codeError{code: code, args: args}
+}
+
+// NewError code のエラー (メッセージはカタログの code の項目に args を入れたもの)
+// ErrorAt に渡すと code と args を引き継ぐ
+func NewError(code string, args ...string) error {
+	return &codeError{code: code, args: args}
+}
+
+// ErrorAt err を tok の位置のエラーにする (コードのないエラーは code、対象は tok)
+// パーサの外で見つけたエラー (オブジェクトの再配置など) も Errors に加える
+func (p *Parser) ErrorAt(code string, tok token.Token, err error) {
+	if e, ok := err.(*codeError); ok {
+		p.parserError(e.code, tok, e.args...)
+		return
+	}
+	p.parserError(code, tok)
+}
+
+// errorCode err のコード (コードのないエラーは空文字列)
+func errorCode(err error) string {
+	if e, ok := err.(*codeError); ok {
+		return e.code
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+)
+
+// condition IF〜ENDIF の状態
+type condition struct {
+	tok    token.Token //IF の Token
+	active bool        //現在の部分をアセンブルする
+	taken  bool        //IF または ELSE のどちらかをアセンブルした (外側が無効なら true)
+	inElse bool
+}
+
+// DefineConstants `NAME=VALUE` をカンマ・空白区切りで並べた定数を ParseProgram の前に定義する
+// VALUE を省略すると 1。ソース中の同名の EQU より優先する
+// 誤りは 0 行目のエラーとしても記録する (ParseProgram がエラーを返す)
+func (p *Parser) DefineConstants(defs string) error {
+	for _, def := range strings.FieldsFunc(defs, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' }) {
+		name, value := def, "1"
+		if i := strings.IndexByte(def, '='); i >= 0 {
+			name, value = def[:i], def[i+1:]
+		}
+		var code, arg string
+		v, err := parseValue(value)
+		switch {
+		case token.LookupInst(name) != token.LABEL || !isLabel(name):
+			code, arg = CodeConstantName, name
+		case err != nil:
+			code, arg = CodeConstantValue, def
+		default:
+			if _, ok := p.symbolTable.DefineConstant(name, v, 0); !ok {
+				code, arg = CodeDuplicateLabel, name
+			}
+		}
+		if code != "" {
+			p.parserError(code, token.Token{}, arg)
+			return NewError(code, arg)
+		}
+		p.predefined[name] = true
+	}
+	return nil
+}
+
+// equStatment `NAME EQU 値` 値は10進数・#16進数・定義済みの定数
+// 定数は番地を表さないので、命令のアドレスに書いても再配置されない
+func (p *Parser) equStatment() {
+	row := p.curRow
+	defer p.skipRow(row)
+	if p.curTokenIs(token.EQU) {
+		p.parserError(CodeEquLabel, p.curToken)
+		return
+	}
+	label := p.curToken
+	p.nextToken()
+	if p.peekRow != row || p.peekTokenIs(token.EOF) {
+		p.parserError(CodeMissingValue, label, "EQU")
+		return
+	}
+	p.nextToken()
+	value, ok := p.constantValue(p.curToken)
+	if !ok || p.predefined[label.Literal] {
+		return
+	}
+	if _, ok := p.symbolTable.DefineConstant(label.Literal, value, label.Line); !ok {
+		p.parserError(CodeDuplicateLabel, label)
+	}
+}
+
+// conditionalStatment IF・ELSE・ENDIF の行と、IF の条件が成り立たない部分の行を処理する
+// `IF 値` 値が 0 以外なら ELSE または ENDIF までをアセンブルする
+func (p *Parser) conditionalStatment() bool {
+	if p.curTokenIs(token.LABEL) && (p.peekTokenIs(token.IF) || p.peekTokenIs(token.ELSE) || p.peekTokenIs(token.ENDIF)) {
+		p.parserError(CodeDirectiveLabel, p.curToken, p.peekToken.Literal, p.curToken.Literal)
+		p.nextToken()
+	}
+	active := p.active()
+	row := p.curRow
+	switch p.curToken.Type {
+	case token.IF:
+		c := condition{tok: p.curToken, taken: !active}
+		if active {
+			if p.peekRow != row || p.peekTokenIs(token.EOF) {
+				p.parserError(CodeMissingValue, p.curToken)
+			} else {
+				p.nextToken()
+				value, _ := p.constantValue(p.curToken)
+				c.active, c.taken = value != 0, value != 0
+			}
+		}
+		p.conditions = append(p.conditions, c)
+	case token.ELSE:
+		if len(p.conditions) == 0 || p.conditions[len(p.conditions)-1].inElse {
+			p.parserError(CodeElse, p.curToken)
+			break
+		}
+		c := &p.conditions[len(p.conditions)-1]
+		c.active, c.taken, c.inElse = !c.taken, true, true
+	case token.ENDIF:
+		if len(p.conditions) == 0 {
+			p.parserError(CodeEndif, p.curToken)
+			break
+		}
+		p.conditions = p.conditions[:len(p.conditions)-1]
+	default:
+		if active {
+			return false
+		}
+	}
+	p.skipRow(row)
+	return true
+}
+
+// active IF の条件が成り立っている (アセンブルする) 部分
+func (p *Parser) active() bool {
+	return len(p.conditions) == 0 || p.conditions[len(p.conditions)-1].active
+}
+
+// endConditions ENDIF のない IF をエラーにする
+func (p *Parser) endConditions() {
+	for _, c := range p.conditions {
+		p.parserError(CodeUnclosedIf, c.tok)
+	}
+	p.conditions = nil
+}
+
+// constantValue 10進数・#16進数・定義済みの定数、またはそれらの式の値
+func (p *Parser) constantValue(tok token.Token) (uint16, bool) {
+	switch tok.Type {
+	case token.INT:
+		v, err := parseValue(tok.Literal)
+		if err != nil {
+			p.parserError(CodeInvalidNumber, tok)
+			return 0, false
+		}
+		return v, true
+	case token.HEX:
+		v, err := p.hexToAddress(tok)
+		return v, err == nil
+	case token.LABEL:
+		e, err := ParseExpr(tok.Literal)
+		if err != nil {
+			// 式の誤りは mergeExpression で報告済み
+			return 0, false
+		}
+		v, _, err := e.Eval(func(label string) (uint16, string, error) {
+			sy, ok := p.symbolTable.Resolve(label)
+			if !ok || sy.Kind != symbol.KindConstant {
+				return 0, "", errorf(CodeNotConstant, label)
+			}
+			return sy.Address, "", nil
+		})
+		if err == nil {
+			return v, true
+		}
+		p.ErrorAt(CodeConstant, tok, err)
+		return 0, false
+	}
+	p.parserError(CodeConstant, tok)
+	return 0, false
+}
+
+// skipRow row の残りの Token を読み飛ばす
+func (p *Parser) skipRow(row int) {
+	for p.curRow == row && !p.curTokenIs(token.EOF) {
+		p.nextToken()
+	}
+}
+
+func isLabel(s string) bool {
+	if s == "" || s[0] < 'A' || s[0] > 'Z' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !('A' <= s[i] && s[i] <= 'Z' || '0' <= s[i] && s[i] <= '9') {
+			return false
+		}
+	}
+	return true
+}
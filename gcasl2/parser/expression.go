@@ -0,0 +1,319 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+)
+
+// Local 同じソースのラベル・リテラルの番地の基準 (Expr.Eval の base)
+const Local = "."
+
+// Expr アドレス式 `BUF+1`・`LEN*2`・`(TAIL-HEAD)/2`
+// ラベル・10進数・#16進数・EQU の定数を + - * / と括弧で計算する
+type Expr struct {
+	src  string
+	root *exprNode
+}
+
+// exprNode op が空なら tok は数値・ラベル、left が nil の MINUS・PLUS は単項演算
+type exprNode struct {
+	op          token.TokenType
+	tok         token.Token
+	left, right *exprNode
+}
+
+// Resolver ラベルの値と番地の基準 (定数は空文字列)
+// 同じ基準の番地どうしの差は定数になる
+type Resolver func(label string) (value uint16, base string, err error)
+
+// ParseExpr AddrLabel (ラベル・リテラル・式) を式として解析する
+func ParseExpr(s string) (*Expr, error) {
+	e := &Expr{src: s}
+	if strings.HasPrefix(s, "=") || !strings.ContainsAny(s, "+-*/()") {
+		e.root = &exprNode{tok: token.Token{Type: token.LABEL, Literal: s}}
+		return e, nil
+	}
+	toks := scanExpr(s)
+	root, rest := parseSum(toks)
+	if root == nil || len(rest) != 0 {
+		return nil, errorf(CodeExpr, s)
+	}
+	e.root = root
+	return e, nil
+}
+
+// scanExpr 演算子と、演算子で区切られた数値・ラベル
+func scanExpr(s string) []token.Token {
+	var toks []token.Token
+	for i := 0; i < len(s); {
+		if t, ok := exprOperators[s[i]]; ok {
+			toks = append(toks, token.Token{Type: t, Literal: s[i : i+1]})
+			i++
+			continue
+		}
+		j := i
+		for j < len(s) && exprOperators[s[j]] == "" {
+			j++
+		}
+		tok := token.Token{Type: token.LABEL, Literal: s[i:j]}
+		switch {
+		case s[i] == '#':
+			tok.Type = token.HEX
+		case '0' <= s[i] && s[i] <= '9':
+			tok.Type = token.INT
+		}
+		toks = append(toks, tok)
+		i = j
+	}
+	return toks
+}
+
+var exprOperators = map[byte]token.TokenType{
+	'+': token.PLUS, '-': token.MINUS, '*': token.ASTERISK, '/': token.SLASH, '(': token.LPAREN, ')': token.RPAREN,
+}
+
+// parseSum 項 {(+|-) 項}
+func parseSum(toks []token.Token) (*exprNode, []token.Token) {
+	left, toks := parseProduct(toks)
+	for left != nil && len(toks) > 0 && (toks[0].Type == token.PLUS || toks[0].Type == token.MINUS) {
+		op := toks[0].Type
+		var right *exprNode
+		right, toks = parseProduct(toks[1:])
+		if right == nil {
+			return nil, toks
+		}
+		left = &exprNode{op: op, left: left, right: right}
+	}
+	return left, toks
+}
+
+// parseProduct 因子 {(*|/) 因子}
+func parseProduct(toks []token.Token) (*exprNode, []token.Token) {
+	left, toks := parseFactor(toks)
+	for left != nil && len(toks) > 0 && (toks[0].Type == token.ASTERISK || toks[0].Type == token.SLASH) {
+		op := toks[0].Type
+		var right *exprNode
+		right, toks = parseFactor(toks[1:])
+		if right == nil {
+			return nil, toks
+		}
+		left = &exprNode{op: op, left: left, right: right}
+	}
+	return left, toks
+}
+
+// parseFactor [+|-] 因子・数値・ラベル・(式)
+func parseFactor(toks []token.Token) (*exprNode, []token.Token) {
+	if len(toks) == 0 {
+		return nil, toks
+	}
+	switch toks[0].Type {
+	case token.PLUS, token.MINUS:
+		right, rest := parseFactor(toks[1:])
+		if right == nil {
+			return nil, rest
+		}
+		return &exprNode{op: toks[0].Type, right: right}, rest
+	case token.LPAREN:
+		node, rest := parseSum(toks[1:])
+		if node == nil || len(rest) == 0 || rest[0].Type != token.RPAREN {
+			return nil, rest
+		}
+		return node, rest[1:]
+	case token.INT, token.HEX, token.LABEL:
+		return &exprNode{tok: toks[0]}, toks[1:]
+	}
+	return nil, toks
+}
+
+// Labels 式が参照するラベル・リテラル (出現順、重複なし)
+func (e *Expr) Labels() []string {
+	var labels []string
+	var walk func(n *exprNode)
+	walk = func(n *exprNode) {
+		if n == nil {
+			return
+		}
+		if n.op == "" && n.tok.Type == token.LABEL && !contains(labels, n.tok.Literal) {
+			labels = append(labels, n.tok.Literal)
+		}
+		walk(n.left)
+		walk(n.right)
+	}
+	walk(e.root)
+	return labels
+}
+
+// exprValue 計算途中の値と、番地の基準ごとの係数
+type exprValue struct {
+	v     int
+	bases map[string]int
+}
+
+// Eval resolve でラベルを解決して計算する
+// 番地を含む式は「番地+定数」「番地-番地」のように、結果が番地1つ分か定数になる形でなければいけない
+// base は結果の番地の基準 (定数は空文字列)
+func (e *Expr) Eval(resolve Resolver) (value uint16, base string, err error) {
+	x, err := e.eval(e.root, resolve)
+	if err != nil {
+		return 0, "", err
+	}
+	for b, n := range x.bases {
+		switch {
+		case n == 0:
+		case n == 1 && base == "":
+			base = b
+		default:
+			return 0, "", errorf(CodeExprAddress, e.src)
+		}
+	}
+	return uint16(x.v), base, nil
+}
+
+func (e *Expr) eval(n *exprNode, resolve Resolver) (exprValue, error) {
+	if n.op == "" {
+		switch n.tok.Type {
+		case token.INT, token.HEX:
+			v, err := parseValue(n.tok.Literal)
+			if err != nil {
+				return exprValue{}, errorf(CodeInvalidNumber, n.tok.Literal)
+			}
+			return exprValue{v: int(v)}, nil
+		}
+		v, base, err := resolve(n.tok.Literal)
+		if err != nil {
+			return exprValue{}, err
+		}
+		x := exprValue{v: int(v)}
+		if base != "" {
+			x.bases = map[string]int{base: 1}
+		}
+		return x, nil
+	}
+	x := exprValue{}
+	if n.left != nil {
+		var err error
+		if x, err = e.eval(n.left, resolve); err != nil {
+			return exprValue{}, err
+		}
+	}
+	y, err := e.eval(n.right, resolve)
+	if err != nil {
+		return exprValue{}, err
+	}
+	switch n.op {
+	case token.PLUS:
+		return exprValue{v: x.v + y.v, bases: addBases(x.bases, y.bases, 1)}, nil
+	case token.MINUS:
+		return exprValue{v: x.v - y.v, bases: addBases(x.bases, y.bases, -1)}, nil
+	}
+	if len(x.bases) != 0 || len(y.bases) != 0 {
+		return exprValue{}, errorf(CodeExprProduct, e.src)
+	}
+	if n.op == token.ASTERISK {
+		return exprValue{v: x.v * y.v}, nil
+	}
+	if y.v == 0 {
+		return exprValue{}, errorf(CodeExprDivide, e.src)
+	}
+	return exprValue{v: x.v / y.v}, nil
+}
+
+// addBases x + sign*y の係数 (0 になった基準は除く)
+func addBases(x, y map[string]int, sign int) map[string]int {
+	if len(x) == 0 && len(y) == 0 {
+		return nil
+	}
+	bases := map[string]int{}
+	for b, n := range x {
+		bases[b] += n
+	}
+	for b, n := range y {
+		bases[b] += sign * n
+	}
+	for b, n := range bases {
+		if n == 0 {
+			delete(bases, b)
+		}
+	}
+	return bases
+}
+
+// resolveAddress scope のプログラムから参照した AddrLabel の値
+func (p *Parser) resolveAddress(scope int, s string) (uint16, error) {
+	e, err := ParseExpr(s)
+	if err != nil {
+		return 0, err
+	}
+	v, _, err := e.Eval(func(label string) (uint16, string, error) {
+		sy, ok := p.symbolTable.ResolveIn(scope, label)
+		switch {
+		case !ok:
+			return 0, "", errorf(CodeUndefined, label)
+		case sy.Kind == symbol.KindConstant:
+			return sy.Address, "", nil
+		}
+		return sy.Address, Local, nil
+	})
+	return v, err
+}
+
+// mergeExpression q から始まる同じ行の式を1つの LABEL Token にまとめる (Literal は空白を除いた式、範囲は式全体)
+// 式でなければ q をそのまま返す。式の誤り (`BUF+` など) はここで報告する
+func (p *Parser) mergeExpression(q queued) queued {
+	operand := false //直前までで数値・ラベル・(式) が完結している
+	depth := 0       //閉じていない括弧
+	switch q.tok.Type {
+	case token.LABEL, token.INT, token.HEX:
+		operand = true
+	case token.LPAREN:
+		depth++
+	case token.PLUS, token.MINUS:
+	default:
+		return q
+	}
+	literal, end := q.tok.Literal, q.tok.EndColumn
+	for {
+		next := p.pull()
+		accept := next.row == q.row
+		if accept && operand {
+			switch t := next.tok; {
+			case t.Type == token.PLUS || t.Type == token.MINUS || t.Type == token.ASTERISK || t.Type == token.SLASH:
+				operand = false
+			case t.Type == token.INT && strings.HasPrefix(t.Literal, "-"):
+				// BUF-1 は LABEL と負の INT になる
+			case t.Type == token.RPAREN && depth > 0:
+				depth--
+			default:
+				accept = false
+			}
+		} else if accept {
+			switch next.tok.Type {
+			case token.LPAREN:
+				depth++
+			case token.PLUS, token.MINUS:
+			case token.LABEL, token.INT, token.HEX:
+				operand = true
+			default:
+				accept = false
+			}
+		}
+		if !accept {
+			p.pending = append([]queued{next}, p.pending...)
+			break
+		}
+		literal += next.tok.Literal
+		end = next.tok.EndColumn
+	}
+	if literal == q.tok.Literal {
+		return q
+	}
+	q.tok.Type, q.tok.Literal, q.tok.EndColumn = token.LABEL, literal, end
+	if _, err := ParseExpr(literal); err != nil && p.active() {
+		// IF の条件が成り立たない部分の行はアセンブルしないので報告しない
+		p.ErrorAt(CodeExpr, q.tok, err)
+	}
+	return q
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		src    string
+		labels []string
+		err    string
+	}{
+		{"BUF", []string{"BUF"}, ""},
+		{"='A+B'", []string{"='A+B'"}, ""},
+		{"BUF+1", []string{"BUF"}, ""},
+		{"(TAIL-HEAD)/2", []string{"TAIL", "HEAD"}, ""},
+		{"BUF+N*BUF", []string{"BUF", "N"}, ""},
+		{"-N", []string{"N"}, ""},
+		{"BUF+", nil, CodeExpr},
+		{"(BUF", nil, CodeExpr},
+		{"BUF)", nil, CodeExpr},
+		{"*2", nil, CodeExpr},
+	}
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.src)
+		if errorCode(err) != tt.err {
+			t.Errorf("%s : %v, want %s", tt.src, err, tt.err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(e.Labels(), tt.labels) {
+			t.Errorf("%s : %v, want %v", tt.src, e.Labels(), tt.labels)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	// BUF・HEAD・TAIL は同じソースの番地、N は定数、EXT は外部シンボル
+	values := map[string]struct {
+		v    uint16
+		base string
+	}{"BUF": {10, Local}, "HEAD": {12, Local}, "TAIL": {20, Local}, "N": {3, ""}, "EXT": {0, "EXT"}}
+	resolve := func(label string) (uint16, string, error) {
+		if x, ok := values[label]; ok {
+			return x.v, x.base, nil
+		}
+		return 0, "", errorf(CodeUndefined, label)
+	}
+	tests := []struct {
+		src   string
+		value uint16
+		base  string
+		err   string
+	}{
+		{"BUF+1", 11, Local, ""},
+		{"BUF-1", 9, Local, ""},
+		{"N+BUF", 13, Local, ""},
+		{"(TAIL-HEAD)/2", 4, "", ""},
+		{"2+3*4", 14, "", ""},
+		{"(2+3)*4", 20, "", ""},
+		{"#000A+N", 13, "", ""},
+		{"-N", 0xFFFD, "", ""},
+		{"EXT+N", 3, "EXT", ""},
+		{"TAIL-HEAD+BUF", 18, Local, ""},
+		{"BUF*2", 0, "", CodeExprProduct},
+		{"N/0", 0, "", CodeExprDivide},
+		{"BUF+TAIL", 0, "", CodeExprAddress},
+		{"EXT-BUF", 0, "", CodeExprAddress},
+		{"NONE+1", 0, "", CodeUndefined},
+		{"70000+1", 0, "", CodeInvalidNumber},
+	}
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.src)
+		if err != nil {
+			t.Errorf("%s : %v", tt.src, err)
+			continue
+		}
+		v, base, err := e.Eval(resolve)
+		if errorCode(err) != tt.err || err == nil && (v != tt.value || base != tt.base) {
+			t.Errorf("%s : %d %q %v, want %d %q %s", tt.src, v, base, err, tt.value, tt.base, tt.err)
+		}
+	}
+}
+
+func TestExpressionOperand(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		words []uint16
+		err   []string
+	}{
+		{"address", "MAIN\tSTART\n\tLD\tGR1,TBL+1\n\tRET\nTBL\tDC\t1,2\n\tEND\n", []uint16{0, 0x1010, 5, 0x8100, 1, 2, 0}, nil},
+		{"spaces", "MAIN\tSTART\n\tLD\tGR1,TBL + 1,GR2\n\tRET\nTBL\tDC\t1,2\n\tEND\n", []uint16{0, 0x1012, 5, 0x8100, 1, 2, 0}, nil},
+		{"length", "MAIN\tSTART\n\tLAD\tGR1,(TAIL-TBL)/1\n\tRET\nTBL\tDC\t1,2\nTAIL\tDC\t0\n\tEND\n", []uint16{0, 0x1210, 2, 0x8100, 1, 2, 0, 0}, nil},
+		{"dc", "N\tEQU\t2\nMAIN\tSTART\n\tDC\tN*3,MAIN+N\n\tEND\n", []uint16{0, 6, 2, 0}, nil},
+		{"incomplete", "MAIN\tSTART\n\tLD\tGR1,TBL+\n\tRET\nTBL\tDC\t1\n\tEND\n", nil, []string{"2:E0501"}},
+		{"product", "MAIN\tSTART\n\tLD\tGR1,TBL*2\n\tRET\nTBL\tDC\t1\n\tEND\n", nil, []string{"2:E0502"}},
+		{"divide", "MAIN\tSTART\n\tLAD\tGR1,4/0\n\tRET\n\tEND\n", nil, []string{"2:E0503"}},
+		{"two addresses", "MAIN\tSTART\n\tLD\tGR1,TBL+MAIN\n\tRET\nTBL\tDC\t1\n\tEND\n", nil, []string{"2:E0504"}},
+		{"undefined", "MAIN\tSTART\n\tLD\tGR1,NONE+1\n\tRET\n\tEND\n", nil, []string{"2:E0002"}},
+	}
+	for _, tt := range tests {
+		words, p, _ := assemble(tt.src)
+		if got := errorCodes(p); !reflect.DeepEqual(got, tt.err) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.err)
+			continue
+		}
+		if tt.err == nil && !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("%s : %04X, want %04X", tt.name, words, tt.words)
+		}
+	}
+}
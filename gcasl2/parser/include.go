@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+)
+
+// Includer `INCLUDE 'name'` のソースを返す
+type Includer func(name string) (string, error)
+
+// SetIncluder INCLUDE のソースの探し方を指定する (ParseProgram の前に呼ぶ)
+func (p *Parser) SetIncluder(f Includer) {
+	p.includer = f
+}
+
+// includeStatment `INCLUDE 'name'` の行を name のソースに置き換える
+// 取り込んだ Token の位置は INCLUDE の行の 'name' になり、ファイル内の位置は Token.Include に残す
+func (p *Parser) includeStatment() {
+	row := p.curRow
+	defer p.skipRow(row)
+	if !p.curTokenIs(token.INCLUDE) {
+		p.parserError(CodeDirectiveLabel, p.curToken, "INCLUDE", p.curToken.Literal)
+		return
+	}
+	directive := p.curToken
+	if p.peekRow != row || !p.peekTokenIs(token.STRING) || len(p.peekToken.Literal) < 3 || !strings.HasSuffix(p.peekToken.Literal, "'") {
+		p.parserError(CodeIncludeOperand, directive)
+		return
+	}
+	operand := p.peekToken
+	name := operand.Literal[1 : len(operand.Literal)-1]
+	for i := directive.Include; i != nil; i = i.Parent {
+		if i.File == name {
+			p.parserError(CodeIncludeCycle, operand, name)
+			return
+		}
+	}
+	if p.includer == nil {
+		p.parserError(CodeIncludeDisabled, operand, name)
+		return
+	}
+	src, err := p.includer(name)
+	if err != nil {
+		p.parserError(CodeIncludeSource, operand, name, err.Error())
+		return
+	}
+	var included []queued
+	l := lexer.New(src)
+	line := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Line != line {
+			line = tok.Line
+			p.rows--
+		}
+		tok.Include = &token.Include{File: name, Line: tok.Line, Column: tok.Column, Parent: directive.Include}
+		tok.Line, tok.Column, tok.EndColumn = operand.Line, operand.Column, operand.EndColumn
+		included = append(included, queued{tok: tok, row: p.rows, depth: p.curDepth})
+	}
+	p.skipRow(row)
+	// 次の行の先頭 (p.curToken) より前に入れる
+	next := []queued{{tok: p.curToken, row: p.curRow, depth: p.curDepth}, {tok: p.peekToken, row: p.peekRow, depth: p.peekDepth}}
+	p.pending = append(append(included, next...), p.pending...)
+	p.peekToken, p.peekRow, p.peekDepth = token.Token{}, 0, 0
+	p.nextToken()
+	p.nextToken()
+}
@@ -0,0 +1,315 @@
+package parser
+
+import (
+	"strconv"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+)
+
+// builtinMacros IN・OUT・RPUSH・RPOP の定義
+// SVC #703A・#02AB は COMET II シミュレータの入力・出力
+const builtinMacros = `
+IN	MACRO	&BUF,&LEN
+	PUSH	0,GR1
+	PUSH	0,GR2
+	LAD	GR1,&BUF
+	LAD	GR2,&LEN
+	SVC	#703A
+	POP	GR2
+	POP	GR1
+	MEND
+OUT	MACRO	&BUF,&LEN
+	PUSH	0,GR1
+	PUSH	0,GR2
+	LAD	GR1,&BUF
+	LAD	GR2,&LEN
+	SVC	#02AB
+	POP	GR2
+	POP	GR1
+	MEND
+RPUSH	MACRO
+	PUSH	0,GR1
+	PUSH	0,GR2
+	PUSH	0,GR3
+	PUSH	0,GR4
+	PUSH	0,GR5
+	PUSH	0,GR6
+	PUSH	0,GR7
+	MEND
+RPOP	MACRO
+	POP	GR7
+	POP	GR6
+	POP	GR5
+	POP	GR4
+	POP	GR3
+	POP	GR2
+	POP	GR1
+	MEND
+`
+
+// maxMacroDepth マクロ展開の入れ子の上限 (再帰の検出)
+const maxMacroDepth = 16
+
+// macro `NAME MACRO &A,&B` 〜 `MEND` で定義したマクロ
+type macro struct {
+	Name   string
+	Params []string
+	Body   []queued //MEND を除く本体
+}
+
+// queued 字句解析の結果またはマクロの展開結果の Token
+// row は行の区別 (ソースの Token は行番号、展開した Token は展開ごとに負の番号)
+type queued struct {
+	tok   token.Token
+	row   int
+	depth int //マクロ展開の入れ子の深さ
+}
+
+// pull 展開済みの Token があればそれを、なければ Lexer の次の Token を返す
+func (p *Parser) pull() queued {
+	if len(p.pending) > 0 {
+		q := p.pending[0]
+		p.pending = p.pending[1:]
+		return q
+	}
+	tok := p.l.NextToken()
+	return queued{tok: tok, row: tok.Line}
+}
+
+// defineBuiltinMacros builtinMacros を定義する
+func (p *Parser) defineBuiltinMacros() {
+	l := p.l
+	p.l = lexer.New(builtinMacros)
+	p.nextToken()
+	p.nextToken()
+	for !p.curTokenIs(token.EOF) {
+		p.macroStatment()
+	}
+	p.l = l
+}
+
+// isMacroStatment MACRO・MEND の行
+func (p *Parser) isMacroStatment() bool {
+	return p.curTokenIs(token.MACRO) || p.curTokenIs(token.MEND) || p.peekTokenIs(token.MACRO)
+}
+
+// macroStatment `NAME MACRO [&A[,&B]...]` から対応する MEND までをマクロとして定義する
+// 本体の中の MACRO〜MEND は入れ子の定義として本体に含め、外側のマクロを展開したときに定義する
+func (p *Parser) macroStatment() {
+	if p.curTokenIs(token.MEND) {
+		p.parserError(CodeMend, p.curToken)
+		p.nextToken()
+		return
+	}
+	ok := true
+	m := &macro{}
+	header := p.curToken //マクロ名 (ない場合は MACRO)
+	if p.curTokenIs(token.MACRO) {
+		p.parserError(CodeMacroName, p.curToken)
+		ok = false
+	} else {
+		m.Name = p.curToken.Literal
+		if !p.curTokenIs(token.LABEL) && !p.curTokenIs(token.IN) && !p.curTokenIs(token.OUT) && !p.curTokenIs(token.RPUSH) && !p.curTokenIs(token.RPOP) {
+			p.parserError(CodeMacroReserved, p.curToken, m.Name)
+			ok = false
+		}
+		p.nextToken()
+	}
+	row := p.curRow
+	for p.peekRow == row && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+		if len(m.Params) > 0 {
+			if !p.curTokenIs(token.COMMA) {
+				p.parserError(CodeParamComma, p.curToken)
+				ok = false
+				break
+			}
+			p.nextToken()
+		}
+		if !p.curTokenIs(token.PARAM) {
+			p.parserError(CodeParamName, p.curToken)
+			ok = false
+			break
+		}
+		for _, param := range m.Params {
+			if param == p.curToken.Literal {
+				p.parserError(CodeDuplicateParam, p.curToken)
+				ok = false
+			}
+		}
+		m.Params = append(m.Params, p.curToken.Literal)
+	}
+	for p.peekRow == row && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+	}
+	// 本体はラベルの別名・引数の置き換えのため Token のまま残す
+	p.defining = true
+	p.nextToken()
+	nested := 0
+	for {
+		if p.curTokenIs(token.EOF) {
+			p.parserError(CodeUnclosedMacro, header, m.Name)
+			p.defining = false
+			return
+		}
+		if p.curTokenIs(token.MACRO) {
+			nested++
+		}
+		if p.curTokenIs(token.MEND) {
+			if nested == 0 {
+				break
+			}
+			nested--
+		}
+		q := queued{tok: p.curToken, row: p.curRow, depth: p.curDepth}
+		if nested == 0 && q.tok.Type == token.PARAM && !contains(m.Params, q.tok.Literal) {
+			p.parserError(CodeNotParam, q.tok, q.tok.Literal, m.Name)
+			ok = false
+		}
+		m.Body = append(m.Body, q)
+		p.nextToken()
+	}
+	p.defining = false
+	p.nextToken()
+	if !ok {
+		return
+	}
+	if _, defined := p.macros[m.Name]; defined {
+		p.parserWarning(CodeMacroRedefined, header, m.Name)
+	}
+	p.macros[m.Name] = m
+}
+
+// isMacroCall 現在の Token がマクロの呼び出し (ラベルではない)
+// `NAME 命令` の NAME はラベル、それ以外はマクロ名とみなす
+func (p *Parser) isMacroCall() bool {
+	if _, ok := p.macros[p.curToken.Literal]; !ok {
+		return false
+	}
+	return p.peekRow != p.curRow || !p.isInstruction(p.peekToken)
+}
+
+// isInstruction 命令・擬似命令・マクロ名
+func (p *Parser) isInstruction(tok token.Token) bool {
+	if _, ok := p.instSet[tok.Type]; ok {
+		return true
+	}
+	_, ok := p.macros[tok.Literal]
+	return ok
+}
+
+// expandMacro マクロ呼び出しの行を本体に置き換える
+// 引数を &NAME に代入し、本体で定義したラベルは展開ごとに NAME.n の別名にする
+// 展開した行の行番号はすべて呼び出しの行になる
+func (p *Parser) expandMacro(label *token.Token) bool {
+	m := p.macros[p.curToken.Literal]
+	call := p.curToken
+	if p.curDepth >= maxMacroDepth {
+		p.parserError(CodeMacroDepth, call)
+		return false
+	}
+	// 引数 (カンマ区切り、呼び出しと同じ行の Token)
+	row := p.curRow
+	var args [][]token.Token
+	if p.peekRow == row && !p.peekTokenIs(token.EOF) {
+		args = append(args, nil)
+	}
+	for p.peekRow == row && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+		if p.curTokenIs(token.COMMA) {
+			args = append(args, nil)
+			continue
+		}
+		args[len(args)-1] = append(args[len(args)-1], p.curToken)
+	}
+	if len(args) > len(m.Params) {
+		p.parserError(CodeMacroArgs, call, call.Literal, strconv.Itoa(len(m.Params)))
+		return false
+	}
+
+	p.expansions++
+	locals := map[string]string{}
+	rows := map[int]int{}
+	var body [][]queued
+	for _, q := range m.Body {
+		if _, ok := rows[q.row]; !ok {
+			p.rows--
+			rows[q.row] = p.rows
+			body = append(body, nil)
+		}
+		body[len(body)-1] = append(body[len(body)-1], q)
+	}
+	for _, r := range body {
+		if name, ok := p.rowLabel(r); ok {
+			locals[name] = name + "." + strconv.Itoa(p.expansions)
+		}
+	}
+	var expanded []queued
+	if label != nil {
+		if len(body) > 0 {
+			if _, ok := p.rowLabel(body[0]); !ok {
+				expanded = append(expanded, queued{tok: *label, row: rows[body[0][0].row], depth: p.curDepth + 1})
+				label = nil
+			}
+		}
+		if label != nil {
+			// 本体の先頭行にラベルがあるときは呼び出しのラベルを直接定義する
+			if _, ok := p.symbolTable.DefineLine(label.Literal, p.byteAdress, label.Line); !ok {
+				p.parserError(CodeDuplicateLabel, *label)
+			}
+		}
+	}
+	for _, q := range m.Body {
+		q.row, q.depth = rows[q.row], p.curDepth+1
+		q.tok.Line, q.tok.Column, q.tok.EndColumn, q.tok.Include = call.Line, call.Column, call.EndColumn, call.Include
+		switch {
+		case q.tok.Type == token.PARAM && contains(m.Params, q.tok.Literal):
+			for i, param := range m.Params {
+				if param != q.tok.Literal || i >= len(args) {
+					continue
+				}
+				for _, arg := range args[i] {
+					arg.Line, arg.Include = call.Line, call.Include
+					expanded = append(expanded, queued{tok: arg, row: q.row, depth: q.depth})
+				}
+			}
+			continue
+		case q.tok.Type == token.LABEL && locals[q.tok.Literal] != "":
+			q.tok.Literal = locals[q.tok.Literal]
+		}
+		expanded = append(expanded, q)
+	}
+
+	peek := queued{tok: p.peekToken, row: p.peekRow, depth: p.peekDepth}
+	p.pending = append(append(expanded, peek), p.pending...)
+	q := p.pull()
+	p.peekToken, p.peekRow, p.peekDepth = q.tok, q.row, q.depth
+	p.nextToken()
+	return true
+}
+
+// rowLabel 本体の1行の先頭がラベルの定義ならその名前
+func (p *Parser) rowLabel(row []queued) (string, bool) {
+	if len(row) == 0 || row[0].tok.Type != token.LABEL {
+		return "", false
+	}
+	if len(row) > 1 && row[1].tok.Type == token.MACRO {
+		// 入れ子のマクロ定義の名前は別名にしない
+		return "", false
+	}
+	if _, ok := p.macros[row[0].tok.Literal]; ok && (len(row) == 1 || !p.isInstruction(row[1].tok)) {
+		// マクロの呼び出し
+		return "", false
+	}
+	return row[0].tok.Literal, true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -5,10 +5,10 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/DJSIer/GCASL2/lexer"
-	"github.com/DJSIer/GCASL2/opcode"
-	"github.com/DJSIer/GCASL2/symbol"
-	"github.com/DJSIer/GCASL2/token"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
 )
 
 var registerNumber = map[string]uint8{
@@ -36,19 +36,51 @@ type Parser struct {
 	instSet     map[token.TokenType]functype
 	Excode      []opcode.Opcode
 	LiteralDC   []token.Token
-	line        int //line number
+	line        int            //line number
+	inProgram   bool           //START〜END の間
+	implicit    bool           //START のない命令から始めたプログラム
+	start       *symbol.Symbol //START ラベル
+	startOp     token.Token    //START のオペランド (実行開始番地)
+	entry       *symbol.Symbol //最初のプログラムの START ラベル
+	macros      map[string]*macro
+	pending     []queued //マクロを展開した Token
+	curRow      int
+	peekRow     int
+	curDepth    int
+	peekDepth   int
+	rows        int             //展開した行の番号 (負の数)
+	expansions  int             //マクロを展開した回数 (ローカルラベルの番号)
+	conditions  []condition     //IF の入れ子
+	predefined  map[string]bool //DefineConstants で定義した定数
+	defining    bool            //マクロの本体を読んでいる (式をまとめない)
+	includer    Includer        //INCLUDE のソース (nil なら INCLUDE できない)
 }
 
 // ParserError Parse Error Message struct
 type ParserError struct {
-	Line    int    //line number
-	Message string //ErrorMessage
+	Line      int      //line number
+	Message   string   //ErrorMessage
+	Code      string   `json:",omitempty"` //エラーコード (E0103 など、code.go)
+	Args      []string `json:",omitempty"` //カタログのメッセージの引数
+	Column    int      `json:",omitempty"` //エラーの範囲 (行頭を 1 とするバイト位置、0 なら行全体)
+	EndColumn int      `json:",omitempty"` //範囲の次の文字の位置
+	Include   []string `json:",omitempty"` //INCLUDE したファイルの中の位置 (内側から file:line:column、Line は INCLUDE の行)
+	// Localize で付ける説明と修正方法
+	Explanation string `json:",omitempty"`
+	Fix         string `json:",omitempty"`
 }
 
 // ParserWarning Parse Warning Message struct
 type ParserWarning struct {
-	Line    int    //line number
-	Message string //WarningMessage
+	Line        int      //line number
+	Message     string   //WarningMessage
+	Code        string   `json:",omitempty"` //警告コード (W0001 など)
+	Args        []string `json:",omitempty"`
+	Column      int      `json:",omitempty"` //ParserError.Column と同じ
+	EndColumn   int      `json:",omitempty"`
+	Include     []string `json:",omitempty"` //ParserError.Include と同じ
+	Explanation string   `json:",omitempty"`
+	Fix         string   `json:",omitempty"`
 }
 
 // New Parser New
@@ -91,26 +123,31 @@ func New(l *lexer.Lexer) *Parser {
 		token.END:   p.ENDStatment,
 		token.CALL:  p.CALLStatment,
 		token.SVC:   p.SVCStatment,
-		token.IN:    p.INStatment,
-		token.OUT:   p.OUTStatment,
-		token.RPUSH: p.RPUSHStatment,
-		token.RPOP:  p.RPOPStatment,
 	}
+	p.macros = map[string]*macro{}
+	p.predefined = map[string]bool{}
+	p.defineBuiltinMacros()
 	p.symbolTable = symbol.NewSymbolTable()
 	p.nextToken()
 	p.nextToken()
 	return p
 }
 func (p *Parser) nextToken() {
-	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	p.curToken, p.curRow, p.curDepth = p.peekToken, p.peekRow, p.peekDepth
+	q := p.pull()
+	if q.row == p.curRow && !p.defining {
+		// オペランドの式 (BUF+1 など) は1つの Token にする
+		q = p.mergeExpression(q)
+	}
+	p.peekToken, p.peekRow, p.peekDepth = q.tok, q.row, q.depth
 }
 func (p *Parser) curTokenIs(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
 
+// peekTokenIs 次の Token が同じ行の t か (次の行の Token はオペランドにしない)
 func (p *Parser) peekTokenIs(t token.TokenType) bool {
-	return p.peekToken.Type == t
+	return p.peekToken.Type == t && p.peekRow == p.curRow
 }
 func (p *Parser) expectPeek(t token.TokenType) bool {
 	if p.peekTokenIs(t) {
@@ -130,17 +167,50 @@ func (p *Parser) Errors() []ParserError {
 func (p *Parser) Warnings() []ParserWarning {
 	return p.warnings
 }
+
+// SymbolTable CASL2 Label symbol table
+func (p *Parser) SymbolTable() *symbol.SymbolTable {
+	return p.symbolTable
+}
 func (p *Parser) peekError(t token.TokenType) {
 	e := &ParserError{Line: p.line, Message: fmt.Sprintf("expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)}
 	p.errors = append(p.errors, *e)
 }
-func (p *Parser) parserError(line int, msg string) {
-	e := &ParserError{Line: line, Message: msg}
+
+// parserError tok の位置のエラー (code は code.go の Code...)
+// args はカタログ (catalog.go) のメッセージの引数。省略すると tok.Literal
+// Message はカタログの DefaultLanguage のメッセージ
+func (p *Parser) parserError(code string, tok token.Token, args ...string) {
+	tok = p.errorToken(tok)
+	if len(args) == 0 {
+		args = []string{tok.Literal}
+	}
+	entry, _ := Lookup(code, DefaultLanguage)
+	e := &ParserError{Line: tok.Line, Message: entry.Format(args), Code: code, Args: args, Column: tok.Column, EndColumn: tok.EndColumn, Include: tok.Include.Stack()}
 	p.errors = append(p.errors, *e)
 }
-func (p *Parser) parserWarning(line int, msg string) {
-	e := &ParserWarning{Line: line, Message: msg}
+
+// errorToken エラーの位置の Token
+// 行末でオペランドが足りない : 次の行の Token ではなく行末の位置にする
+func (p *Parser) errorToken(tok token.Token) token.Token {
+	if tok == p.peekToken && p.peekRow != p.curRow {
+		return token.Token{Line: p.curToken.Line, Column: p.curToken.EndColumn, EndColumn: p.curToken.EndColumn, Include: p.curToken.Include}
+	}
+	return tok
+}
+
+// registerError tok がレジスタではないエラー (メッセージの {2} は命令 inst)
+func (p *Parser) registerError(tok token.Token, inst token.Token) {
+	tok = p.errorToken(tok)
+	p.parserError(CodeRegister, tok, tok.Literal, inst.Literal)
+}
+func (p *Parser) parserWarning(code string, tok token.Token, args ...string) {
+	if len(args) == 0 {
+		args = []string{tok.Literal}
+	}
+	entry, _ := Lookup(code, DefaultLanguage)
+	e := &ParserWarning{Line: tok.Line, Message: entry.Format(args), Code: code, Args: args, Column: tok.Column, EndColumn: tok.EndColumn, Include: tok.Include.Stack()}
 	p.warnings = append(p.warnings, *e)
 }
 
@@ -150,18 +220,58 @@ func (p *Parser) ParseProgram() ([]opcode.Opcode, error) {
 	//endCheck := false
 	for !p.curTokenIs(token.EOF) {
 		code := &opcode.Opcode{Length: 1}
+		errors, excode := len(p.errors), len(p.Excode)
+		if p.conditionalStatment() {
+			continue
+		}
+		if p.curTokenIs(token.INCLUDE) || p.peekTokenIs(token.INCLUDE) {
+			p.includeStatment()
+			continue
+		}
+		if p.curTokenIs(token.EQU) || p.peekTokenIs(token.EQU) {
+			p.equStatment()
+			continue
+		}
+		if p.isMacroStatment() {
+			p.macroStatment()
+			continue
+		}
+		// ラベルだけの行は次の行の命令のラベル
+		if p.curTokenIs(token.START) || p.curTokenIs(token.LABEL) && p.peekToken.Type == token.START {
+			p.endImplicit()
+			// START のラベルは新しいプログラムのスコープに定義する
+			p.symbolTable.BeginScope()
+		} else if !p.inProgram && !p.curTokenIs(token.END) && !(p.curTokenIs(token.LABEL) && p.peekToken.Type == token.END) {
+			p.beginImplicit()
+		}
 		//Label
-		if p.curTokenIs(token.LABEL) {
-			sy, flag := p.symbolTable.Define(p.curToken.Literal, p.byteAdress)
+		var label *token.Token
+		if p.curTokenIs(token.LABEL) && !p.isMacroCall() {
+			if _, ok := p.macros[p.peekToken.Literal]; ok {
+				// マクロの呼び出し行のラベルは展開した先頭の命令に付ける
+				tok := p.curToken
+				label = &tok
+				p.nextToken()
+			}
+		}
+		if p.curTokenIs(token.LABEL) && !p.isMacroCall() {
+			sy, flag := p.symbolTable.DefineLine(p.curToken.Literal, p.byteAdress, p.curToken.Line)
 			if flag {
 				code.Label = &sy
 			} else {
-				p.parserError(p.curToken.Line, fmt.Sprintf("重複定義エラー Label : %q", p.curToken.Literal))
-				return nil, fmt.Errorf("LABEL Error")
+				// 重複したラベルは無視して命令の解析を続ける
+				p.parserError(CodeDuplicateLabel, p.curToken)
 			}
 			p.nextToken()
 		}
 		code.Token = p.curToken
+		inst, row := p.curToken, p.curRow
+		if _, ok := p.macros[p.curToken.Literal]; ok {
+			if !p.expandMacro(label) {
+				p.synchronize(inst.Line, row, excode)
+			}
+			continue
+		}
 
 		switch p.curToken.Type {
 		case token.LAD:
@@ -226,22 +336,24 @@ func (p *Parser) ParseProgram() ([]opcode.Opcode, error) {
 			code = p.instSet[p.curToken.Type](code)
 		case token.SVC:
 			code = p.instSet[p.curToken.Type](code)
-		case token.IN:
-			code = p.instSet[p.curToken.Type](code)
-		case token.OUT:
-			code = p.instSet[p.curToken.Type](code)
-		case token.RPUSH:
-			code = p.instSet[p.curToken.Type](code)
-		case token.RPOP:
-			code = p.instSet[p.curToken.Type](code)
 		default:
-			p.parserError(p.curToken.Line, fmt.Sprintf("%q : 解決できません\n", p.curToken.Literal))
+			p.parserError(CodeUnknown, p.curToken)
 			code = nil
 		}
 		if code == nil {
-			return p.Excode, fmt.Errorf("%q : コンパイルエラー", p.curToken)
+			if len(p.errors) == errors {
+				p.parserError(CodeSyntax, inst, p.curToken.Literal)
+			}
+			p.synchronize(inst.Line, row, excode)
+			p.line++
+			continue
 		}
 
+		scope := p.symbolTable.Scope()
+		for i := excode; i < len(p.Excode); i++ {
+			p.Excode[i].Scope = scope
+		}
+		code.Scope = scope
 		p.Excode = append(p.Excode, *code)
 		p.byteAdress += uint16(code.Length)
 
@@ -252,61 +364,130 @@ func (p *Parser) ParseProgram() ([]opcode.Opcode, error) {
 	/*if !endCheck {
 		return p.Excode, fmt.Errorf("%qがありません。", "END")
 	}*/
+	if p.inProgram {
+		p.resolveEntry()
+	}
+	p.endConditions()
+	if len(p.errors) != 0 {
+		return p.Excode, fmt.Errorf("%d件のコンパイルエラーがあります", len(p.errors))
+	}
 	return p.Excode, nil
 }
 
-//LabelToAddress ラベルアドレスの解決
+// synchronize エラーのあった行の残りを読み飛ばし、次の行から解析を再開する
+// エラー行の途中まで出力した機械語は取り消す
+// INCLUDE したファイルの Token は行番号がすべて INCLUDE の行なので、row の行だけを読み飛ばす
+func (p *Parser) synchronize(line, row int, excode int) {
+	for _, op := range p.Excode[excode:] {
+		p.byteAdress -= uint16(op.Length)
+	}
+	p.Excode = p.Excode[:excode]
+	for !p.curTokenIs(token.EOF) && p.curToken.Line <= line {
+		if p.curToken.Include != nil && p.curRow != row {
+			break
+		}
+		p.nextToken()
+	}
+}
+
+// Assemble ParseProgram・LiteralToMemory・LabelToAddress を順に行う
+func (p *Parser) Assemble() ([]opcode.Opcode, error) {
+	code, err := p.ParseProgram()
+	if err != nil {
+		return nil, err
+	}
+	code, err = p.LiteralToMemory(code)
+	if err != nil {
+		return nil, err
+	}
+	return p.LabelToAddress(code)
+}
+
+// LabelToAddress ラベルアドレスの解決
+// AddrLabel はラベル・リテラルまたは式 (BUF+1 など)
 func (p *Parser) LabelToAddress(code []opcode.Opcode) ([]opcode.Opcode, error) {
+	unresolved := false
 	for i, op := range code {
 		if len(op.AddrLabel) != 0 {
-			addr, ok := p.symbolTable.Resolve(op.AddrLabel)
-			if !ok {
-				p.parserError(op.Token.Line, fmt.Sprintf("%qは解決できません", op.AddrLabel))
-				return nil, fmt.Errorf("Labelが解決できません")
+			addr, err := p.resolveAddress(op.Scope, op.AddrLabel)
+			if err != nil {
+				if errorCode(err) != CodeExpr {
+					// 式の誤りは ParseProgram で報告済み
+					p.ErrorAt(CodeUndefined, op.AddrToken, err)
+				}
+				unresolved = true
+				continue
 			}
-			code[i].Addr = addr.Address
+			code[i].Addr = addr
 		}
 	}
+	if unresolved {
+		return nil, fmt.Errorf("Labelが解決できません")
+	}
 	return code, nil
 }
 
-//LiteralToMemory =literal のメモリ追加
+// LiteralToMemory =literal のメモリ追加
+// END で配置されていない (END のない) プログラムのリテラルを code の後に配置する
 func (p *Parser) LiteralToMemory(code []opcode.Opcode) ([]opcode.Opcode, error) {
-	for _, l := range p.LiteralDC {
+	literals := p.LiteralDC
+	p.LiteralDC = nil
+	scope := p.symbolTable.Scope()
+	for _, l := range literals {
 		switch l.Type {
 		case token.EQINT:
-			addr, err := strconv.ParseUint(strings.Replace(l.Literal, "=", "", -1), 0, 16)
+			addr, err := parseValue(strings.TrimPrefix(l.Literal, "="))
 			if err != nil {
-				p.parserError(0, fmt.Sprintf("%q : 解決できません\n", l.Literal))
+				p.parserError(CodeInvalidNumber, l)
 				return code, fmt.Errorf("リテラル解決失敗")
 			}
-			code = append(code, opcode.Opcode{Addr: uint16(addr), Length: 1, Token: token.Token{Literal: "DC"}})
+			code = append(code, opcode.Opcode{Addr: addr, Length: 1, Token: token.Token{Literal: "DC"}, Scope: scope})
 			p.symbolTable.LiteralAddressSet(l.Literal, p.byteAdress)
 			p.byteAdress++
 		case token.EQHEX:
-			addr, err := strconv.ParseUint(strings.Replace(l.Literal, "=#", "", -1), 16, 16)
+			addr, err := parseValue(strings.TrimPrefix(l.Literal, "="))
 			if err != nil {
-				p.parserError(0, fmt.Sprintf("%q : 解決できません\n", l.Literal))
+				p.parserError(CodeInvalidNumber, l)
 				return code, fmt.Errorf("リテラル解決失敗")
 			}
-			code = append(code, opcode.Opcode{Addr: uint16(addr), Length: 1, Token: token.Token{Literal: "DC"}})
+			code = append(code, opcode.Opcode{Addr: addr, Length: 1, Token: token.Token{Literal: "DC"}, Scope: scope})
 			p.symbolTable.LiteralAddressSet(l.Literal, p.byteAdress)
 			p.byteAdress++
+		case token.EQSTRING:
+			// ='ABC' 1文字1語 ('' は ' 1文字)
+			str := strings.TrimPrefix(l.Literal, "=")
+			if len(str) < 3 || !strings.HasSuffix(str, "'") {
+				p.parserError(CodeEmptyString, l)
+				return code, fmt.Errorf("リテラル解決失敗")
+			}
+			p.symbolTable.LiteralAddressSet(l.Literal, p.byteAdress)
+			str = strings.Replace(str[1:len(str)-1], "''", "'", -1)
+			for i := 0; i < len(str); i++ {
+				ch, _ := token.LookupLetter(str[i])
+				code = append(code, opcode.Opcode{Addr: uint16(ch), Length: 1, Token: token.Token{Literal: "DC"}, Scope: scope})
+				p.byteAdress++
+			}
 		}
 	}
 	return code, nil
 }
 
-//DCStatment 定数定義
+// DCStatment 定数定義
+// DC ラベル はラベルの番地 (EQU の定数は値) になる
 func (p *Parser) DCStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x00, Code: 0x0000, Length: 1, Label: code.Label, Token: code.Token}
+	if p.peekRow != p.curRow {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
+		return nil
+	}
 	switch p.peekToken.Type {
 	case token.INT:
-		num, err := strconv.ParseInt(p.peekToken.Literal, 0, 64)
+		num, err := parseValue(p.peekToken.Literal)
 		if err != nil {
+			p.parserError(CodeInvalidNumber, p.peekToken)
 			return nil
 		}
-		code.Addr = uint16(num)
+		code.Addr = num
 	case token.HEX:
 		addr, err := p.hexToAddress(p.peekToken)
 		if err != nil {
@@ -315,8 +496,10 @@ func (p *Parser) DCStatment(code *opcode.Opcode) *opcode.Opcode {
 		code.Addr = addr
 	case token.STRING:
 		code.Addr = p.stringToAddress(code, p.peekToken.Literal)
+	case token.LABEL:
+		code.AddrLabel, code.AddrToken = p.peekToken.Literal, p.peekToken
 	default:
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値でなければいけません。対象 : %q", p.peekToken.Literal))
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -328,14 +511,18 @@ func (p *Parser) DCStatment(code *opcode.Opcode) *opcode.Opcode {
 		p.byteAdress++
 		code = &opcode.Opcode{Op: 0x00, Code: 0x0000, Length: 1, Token: code.Token}
 		p.nextToken()
-
+		if p.peekRow != p.curRow {
+			p.parserError(CodeNumberOrLabel, p.peekToken)
+			return nil
+		}
 		switch p.peekToken.Type {
 		case token.INT:
-			num, err := strconv.ParseInt(p.peekToken.Literal, 0, 64)
+			num, err := parseValue(p.peekToken.Literal)
 			if err != nil {
+				p.parserError(CodeInvalidNumber, p.peekToken)
 				return nil
 			}
-			code.Addr = uint16(num)
+			code.Addr = num
 		case token.HEX:
 			addr, err := p.hexToAddress(p.peekToken)
 			if err != nil {
@@ -344,8 +531,10 @@ func (p *Parser) DCStatment(code *opcode.Opcode) *opcode.Opcode {
 			code.Addr = addr
 		case token.STRING:
 			code.Addr = p.stringToAddress(code, p.peekToken.Literal)
+		case token.LABEL:
+			code.AddrLabel, code.AddrToken = p.peekToken.Literal, p.peekToken
 		default:
-			p.parserError(p.peekToken.Line, fmt.Sprintf("数値でなければいけません。対象 : %q", p.peekToken.Literal))
+			p.parserError(CodeNumberOrLabel, p.peekToken)
 			return nil
 		}
 		p.nextToken()
@@ -353,175 +542,126 @@ func (p *Parser) DCStatment(code *opcode.Opcode) *opcode.Opcode {
 }
 
 // DSStatment 領域確保
-// [LABEL] DS NUM
+// [LABEL] DS NUM (NUM は定義済みの定数でもよい)
 func (p *Parser) DSStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x00, Code: 0x0000, Length: 1, Label: code.Label, Token: code.Token}
+	if p.peekTokenIs(token.LABEL) {
+		p.nextToken()
+		length, ok := p.constantValue(p.curToken)
+		if !ok {
+			return nil
+		}
+		code.Length = int(length)
+		return code
+	}
 	if !p.peekTokenIs(token.INT) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値でなければいけません。対象 : %q", p.peekToken.Literal))
+		p.parserError(CodeNumber, p.peekToken)
 		return nil
 	}
 	p.nextToken()
-	Length, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
-	if err != nil {
+	Length, err := parseValue(p.curToken.Literal)
+	if err != nil || strings.HasPrefix(p.curToken.Literal, "-") {
+		p.parserError(CodeInvalidNumber, p.curToken)
 		return nil
 	}
 	code.Length = int(Length)
 	return code
 }
 
-//INStatment 入力装置から文字データを入力
-func (p *Parser) INStatment(code *opcode.Opcode) *opcode.Opcode {
-	var inStatmentCode []opcode.Opcode
-	code = &opcode.Opcode{Op: 0x70, Code: 0x7001, Length: 2, Token: token.Token{Literal: "PUSH"}}
-	inStatmentCode = append(inStatmentCode, *code)
-	code = &opcode.Opcode{Op: 0x70, Code: 0x7002, Length: 2, Token: token.Token{Literal: "PUSH"}}
-	inStatmentCode = append(inStatmentCode, *code)
-	if !p.peekTokenIs(token.LABEL) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("IN %q INのあとはラベル,数値リテラルでなければいけません 対象：%q", p.peekToken.Literal, p.peekToken.Literal))
-		return nil
-	}
-	firstOp := p.peekToken.Literal
+// STARTStatment `Label START [OP]` - [実行番地]
+// START プログラムの実行番地を定義
+// OP を指定するとプログラム内のラベル OP から実行を開始する (START ラベルのアドレスも OP になる)
+// 1つのソースに START〜END のプログラムを複数書ける
+// ラベルはプログラムごとのスコープに定義され、START のラベルだけが他のプログラムから参照できる
+func (p *Parser) STARTStatment(code *opcode.Opcode) *opcode.Opcode {
 
-	p.nextToken()
-	code = &opcode.Opcode{Op: 0x12, Code: 0x1210, Length: 2, Token: token.Token{Literal: "LAD"}}
-	switch p.curToken.Type {
-	case token.LABEL:
-		code.AddrLabel = p.curToken.Literal
-	}
-	inStatmentCode = append(inStatmentCode, *code)
-	if !p.peekTokenIs(token.COMMA) {
+	if p.inProgram {
+		p.parserError(CodeMissingEnd, p.curToken)
 		return nil
 	}
-	p.nextToken()
-	code = &opcode.Opcode{Op: 0x12, Code: 0x1220, Length: 2, Token: token.Token{Literal: "LAD"}}
-	if !p.peekTokenIs(token.LABEL) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("IN %s,%q \n %qのあとはラベル,数値リテラルでなければいけません 対象：%q", firstOp, p.peekToken.Literal, p.peekToken.Literal, p.peekToken.Literal))
+	p.inProgram = true
+	p.start, p.startOp = nil, token.Token{}
+	if code.Label == nil {
+		p.parserError(CodeStartLabel, p.curToken)
 		return nil
 	}
-	p.nextToken()
-	switch p.curToken.Type {
-	case token.LABEL:
-		code.AddrLabel = p.curToken.Literal
-	}
-	inStatmentCode = append(inStatmentCode, *code)
-	code = &opcode.Opcode{Op: 0xF0, Code: 0xF000, Length: 2, Addr: 0x703A, Token: token.Token{Literal: "SVC"}}
-	inStatmentCode = append(inStatmentCode, *code)
-	code = &opcode.Opcode{Op: 0x71, Code: 0x7120, Length: 1, Token: token.Token{Literal: "POP"}}
-	inStatmentCode = append(inStatmentCode, *code)
-	//p.byteAdress += uint16(code.Length)
-	for _, s := range inStatmentCode {
-		p.Excode = append(p.Excode, s)
-		p.byteAdress += uint16(s.Length)
-	}
-	code = &opcode.Opcode{Op: 0x71, Code: 0x7110, Length: 1, Token: token.Token{Literal: "POP"}}
-	return code
-}
-
-//OUTStatment 入力装置から文字データを入力
-func (p *Parser) OUTStatment(code *opcode.Opcode) *opcode.Opcode {
-	var inStatmentCode []opcode.Opcode
-	code = &opcode.Opcode{Op: 0x70, Code: 0x7001, Length: 2, Token: token.Token{Literal: "PUSH"}}
-	inStatmentCode = append(inStatmentCode, *code)
-	code = &opcode.Opcode{Op: 0x70, Code: 0x7002, Length: 2, Token: token.Token{Literal: "PUSH"}}
-	inStatmentCode = append(inStatmentCode, *code)
-	if !p.peekTokenIs(token.LABEL) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("OUT %q OUTのあとはラベル,数値リテラルでなければいけません 対象：%q", p.peekToken.Literal, p.peekToken.Literal))
+	sy, ok := p.symbolTable.Export(code.Label.Label)
+	if !ok {
+		p.parserError(CodeDuplicateLabel, p.curToken, code.Label.Label)
 		return nil
 	}
-	firstOp := p.peekToken.Literal
-	p.nextToken()
-	code = &opcode.Opcode{Op: 0x12, Code: 0x1210, Length: 2, Token: token.Token{Literal: "LAD"}}
-	switch p.curToken.Type {
-	case token.LABEL:
-		code.AddrLabel = p.curToken.Literal
-	}
-	inStatmentCode = append(inStatmentCode, *code)
-	if !p.peekTokenIs(token.COMMA) {
-		return nil
+	code = &opcode.Opcode{Op: 0x00, Code: 0x0000, Length: 1, Label: &sy, Token: code.Token}
+	p.start = code.Label
+	if p.entry == nil {
+		p.entry = code.Label
 	}
-	p.nextToken()
-	code = &opcode.Opcode{Op: 0x12, Code: 0x1220, Length: 2, Token: token.Token{Literal: "LAD"}}
-	if !p.peekTokenIs(token.LABEL) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("OUT %s,%q \n %qのあとはラベル,数値リテラルでなければいけません 対象：%q", firstOp, p.peekToken.Literal, p.peekToken.Literal, p.peekToken.Literal))
-		return nil
+	if p.peekTokenIs(token.LABEL) {
+		p.nextToken()
+		p.startOp = p.curToken
 	}
-	p.nextToken()
-	switch p.curToken.Type {
-	case token.LABEL:
-		code.AddrLabel = p.curToken.Literal
-	}
-	inStatmentCode = append(inStatmentCode, *code)
-	code = &opcode.Opcode{Op: 0xF0, Code: 0xF000, Length: 2, Addr: 0x02AB, Token: token.Token{Literal: "SVC"}}
-	inStatmentCode = append(inStatmentCode, *code)
-	code = &opcode.Opcode{Op: 0x71, Code: 0x7120, Length: 1, Token: token.Token{Literal: "POP"}}
-	inStatmentCode = append(inStatmentCode, *code)
-	//p.byteAdress += uint16(code.Length)
-	for _, s := range inStatmentCode {
-		p.Excode = append(p.Excode, s)
-		p.byteAdress += uint16(s.Length)
-	}
-	code = &opcode.Opcode{Op: 0x71, Code: 0x7110, Length: 1, Token: token.Token{Literal: "POP"}}
 	return code
 }
 
-// RPUSHStatment RPUSHマクロ
-func (p *Parser) RPUSHStatment(code *opcode.Opcode) *opcode.Opcode {
-	code = &opcode.Opcode{Op: 0x70, Code: 0x7001, Length: 2, Token: token.Token{Literal: "PUSH", Line: code.Token.Line}, Label: code.Label}
-	p.Excode = append(p.Excode, *code)
-	p.byteAdress += uint16(code.Length)
-	for i := 0x7002; i <= 0x7006; i++ {
-		code = &opcode.Opcode{Op: 0x70, Code: uint16(i), Length: 2, Token: code.Token}
-		p.byteAdress += uint16(code.Length)
-		p.Excode = append(p.Excode, *code)
-	}
-	code = &opcode.Opcode{Op: 0x70, Code: 0x7007, Length: 2, Token: code.Token}
-	return code
+// beginImplicit START のない命令 (`LD GR1,GR2` だけのソースなど) を START ラベルのないプログラムとして始める
+// END または次の START までがそのプログラムになる
+func (p *Parser) beginImplicit() {
+	p.symbolTable.BeginScope()
+	p.inProgram, p.implicit = true, true
+	p.start, p.startOp = nil, token.Token{}
 }
 
-// RPOPStatment RPOPマクロ
-func (p *Parser) RPOPStatment(code *opcode.Opcode) *opcode.Opcode {
-	code = &opcode.Opcode{Op: 0x71, Code: 0x7170, Length: 1, Token: token.Token{Literal: "PUSH", Line: code.Token.Line}, Label: code.Label}
-	p.Excode = append(p.Excode, *code)
-	p.byteAdress += uint16(code.Length)
-	for i := 0x7160; i >= 0x7110; i -= 0x10 {
-		code = &opcode.Opcode{Op: 0x71, Code: uint16(i), Length: 1, Token: code.Token}
-		p.byteAdress += uint16(code.Length)
-		p.Excode = append(p.Excode, *code)
+// endImplicit END のないまま START が来たら、START のないプログラムのリテラルを START の前に配置する
+func (p *Parser) endImplicit() {
+	if !p.implicit {
+		return
 	}
-	code = &opcode.Opcode{Op: 0x71, Code: 0x7110, Length: 1, Token: code.Token}
-	return code
+	p.Excode, _ = p.LiteralToMemory(p.Excode)
+	p.inProgram, p.implicit = false, false
 }
 
-// STARTStatment `Label START OP` - [実行番地]
-// START プログラムの実行番地を定義
-func (p *Parser) STARTStatment(code *opcode.Opcode) *opcode.Opcode {
-
-	if p.byteAdress != 0 {
-		p.parserError(p.curToken.Line, fmt.Sprintf("STARTはプログラムの先頭になければいけません。対象 : %q", p.curToken.Literal))
-		return nil
+// resolveEntry START のオペランドをプログラム内のラベルとして解決し、START ラベルのアドレスにする
+func (p *Parser) resolveEntry() {
+	if p.start == nil || p.startOp.Literal == "" {
+		return
 	}
-	if code.Label == nil {
-		p.parserError(p.curToken.Line, fmt.Sprintf("STARTにラベルがありません。対象 : %q", p.curToken.Literal))
-		return nil
+	sy, ok := p.symbolTable.Resolve(p.startOp.Literal)
+	if !ok || sy.Scope != p.symbolTable.Scope() {
+		p.parserError(CodeUndefined, p.startOp)
+		return
 	}
-	sy, ok := p.symbolTable.Resolve(code.Label.Label)
-	if !ok {
-		p.parserError(p.curToken.Line, fmt.Sprintf("STARTにラベルがありません。対象 : %q", p.curToken.Literal))
-		return nil
+	p.start.Address = sy.Address
+	p.symbolTable.SetEntry(p.start.Label, sy.Address)
+}
+
+// Entry 実行開始番地 (最初のプログラムの START のオペランド、省略時は START の番地)
+func (p *Parser) Entry() (uint16, bool) {
+	if p.entry == nil {
+		return 0, false
 	}
-	code = &opcode.Opcode{Op: 0x00, Code: 0x0000, Length: 1, Label: &sy, Token: code.Token}
-	return code
+	return p.entry.Address, true
 }
 
 // ENDStatment `END`
+// プログラム中のリテラルを END の直前に配置する
 func (p *Parser) ENDStatment(code *opcode.Opcode) *opcode.Opcode {
+	if !p.inProgram {
+		p.parserError(CodeEndWithoutStart, p.curToken)
+		return nil
+	}
+	p.inProgram, p.implicit = false, false
+	p.resolveEntry()
 	code = &opcode.Opcode{Op: 0x00, Code: 0x0000, Length: 1, Label: code.Label, Token: code.Token}
+	var err error
+	if p.Excode, err = p.LiteralToMemory(p.Excode); err != nil {
+		return nil
+	}
 	return code
 }
 
 // RETStatment Return from subroutine Parser
 // RET ;PR ← ((SP)),
-//	   ;SP ← (SP) + 1
+//
+//	;SP ← (SP) + 1
 func (p *Parser) RETStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x81, Code: 0x8100, Length: 1, Label: code.Label, Token: code.Token}
 	return code
@@ -530,39 +670,39 @@ func (p *Parser) RETStatment(code *opcode.Opcode) *opcode.Opcode {
 // CALLStatment call subroutine
 func (p *Parser) CALLStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x80, Code: 0x8000, Length: 2, Label: code.Label, Token: code.Token}
-	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) && !p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQINT) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) && !p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) && !p.peekTokenIs(token.EQINT) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 	case token.HEX:
 		addr, err := p.hexToAddress(p.curToken)
 		if err != nil {
 			return nil
 		}
 		code.Addr = uint16(addr)
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 	}
 	if !p.peekTokenIs(token.COMMA) {
 		return code
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q\n", p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -574,39 +714,39 @@ func (p *Parser) CALLStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) SVCStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0xF0, Code: 0xF000, Length: 2, Label: code.Label, Token: code.Token}
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQINT) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) && !p.peekTokenIs(token.EQINT) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 	case token.HEX:
 		addr, err := p.hexToAddress(p.curToken)
 		if err != nil {
 			return nil
 		}
 		code.Addr = uint16(addr)
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 	}
 	if !p.peekTokenIs(token.COMMA) {
 		return code
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q\n", p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -620,21 +760,21 @@ func (p *Parser) SVCStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) LDStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	r1 := p.curToken.Literal
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.REGISTER) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("LD %s,%q の値が数値・レジスタ・ラベルではありません。対象 : %q", r1, p.peekToken.Literal, p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeOperand, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -642,12 +782,12 @@ func (p *Parser) LDStatment(code *opcode.Opcode) *opcode.Opcode {
 	code.Length = 2
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。\n 0~65535が有効な数値です。対象 : %q", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
@@ -667,18 +807,22 @@ func (p *Parser) LDStatment(code *opcode.Opcode) *opcode.Opcode {
 		code.Op = 0x14
 		code.Length = 1
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	default:
@@ -695,37 +839,37 @@ func (p *Parser) LADStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x12, Code: 0x1200, Length: 2, Label: code.Label, Token: code.Token}
 
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	r1 := p.curToken.Literal
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	if !p.expectPeek(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("LAD %s,%q の値が数値・ラベルではありません。対象 : %q", r1, p.peekToken.Literal, p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
-	case token.LABEL, token.EQINT, token.EQHEX:
+		code.Addr = addr
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 	case token.HEX:
 		addr, err := p.hexToAddress(p.curToken)
 		if err != nil {
@@ -733,13 +877,12 @@ func (p *Parser) LADStatment(code *opcode.Opcode) *opcode.Opcode {
 		}
 		code.Addr = addr
 	}
-	r2 := p.curToken.Literal
 	if !p.peekTokenIs(token.COMMA) {
 		return code
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("LAD %s,%s,%qレジスタではありません。対象 : %q\n", r1, r2, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -757,34 +900,34 @@ func (p *Parser) STStatment(code *opcode.Opcode) *opcode.Opcode {
 	if err != nil {
 		return nil
 	}
-	
+
 	r1 := p.curToken.Literal
 
 	if !p.expectPeek(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %s,%q の値が数値・ラベルではありません。対象 : %q", code.Token.Literal, r1, p.peekToken.Literal, p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("%s %s,%q の数値が適正ではありません。\n 数値は0~65535までです。\n対象 : %q\n", code.Token.Literal, r1, p.curToken.Literal, p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
-	case token.LABEL, token.EQINT, token.EQHEX:
+		code.Addr = addr
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 	case token.HEX:
 		addr, err := p.hexToAddress(p.curToken)
 		if err != nil {
@@ -792,13 +935,12 @@ func (p *Parser) STStatment(code *opcode.Opcode) *opcode.Opcode {
 		}
 		code.Addr = addr
 	}
-	r2 := p.curToken.Literal
 	if !p.peekTokenIs(token.COMMA) {
 		return code
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %s,%s,%qレジスタではありません。対象 : %q\n", code.Token.Literal, r1, r2, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -812,22 +954,22 @@ func (p *Parser) STStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) ADDAStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	r1 := p.curToken.Literal
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.REGISTER) &&
 		!p.peekTokenIs(token.LABEL) && !p.peekTokenIs(token.HEX) &&
-		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・レジスタ・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeOperand, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -835,12 +977,12 @@ func (p *Parser) ADDAStatment(code *opcode.Opcode) *opcode.Opcode {
 	code.Length = 2
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
@@ -849,20 +991,20 @@ func (p *Parser) ADDAStatment(code *opcode.Opcode) *opcode.Opcode {
 		code.Op = 0x24
 		code.Length = 1
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
 		if !p.peekTokenIs(token.REGISTER) {
-			p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q", p.peekToken.Literal))
+			p.registerError(p.peekToken, code.Token)
 			return nil
 		}
 		p.nextToken()
@@ -891,22 +1033,22 @@ func (p *Parser) ADDAStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) SUBAStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	r1 := p.curToken.Literal
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.REGISTER) &&
 		!p.peekTokenIs(token.LABEL) && !p.peekTokenIs(token.HEX) &&
-		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・レジスタ・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeOperand, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -915,38 +1057,42 @@ func (p *Parser) SUBAStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	case token.REGISTER:
 		code.Op = 0x25
 		code.Length = 1
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
 		if !p.peekTokenIs(token.REGISTER) {
-			p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q", p.peekToken.Literal))
+			p.registerError(p.peekToken, code.Token)
 			return nil
 		}
 		p.nextToken()
@@ -963,7 +1109,7 @@ func (p *Parser) SUBAStatment(code *opcode.Opcode) *opcode.Opcode {
 		}
 		p.nextToken()
 		if !p.peekTokenIs(token.REGISTER) {
-			p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q", p.peekToken.Literal))
+			p.registerError(p.peekToken, code.Token)
 			return nil
 		}
 		p.nextToken()
@@ -982,22 +1128,22 @@ func (p *Parser) SUBAStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) ADDLStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	r1 := p.curToken.Literal
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.REGISTER) &&
 		!p.peekTokenIs(token.LABEL) && !p.peekTokenIs(token.HEX) &&
-		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・レジスタ・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeOperand, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -1007,38 +1153,42 @@ func (p *Parser) ADDLStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	case token.REGISTER:
 		code.Op = 0x26
 		code.Length = 1
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
 		if !p.peekTokenIs(token.REGISTER) {
-			p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q", p.peekToken.Literal))
+			p.registerError(p.peekToken, code.Token)
 			return nil
 		}
 		p.nextToken()
@@ -1055,7 +1205,7 @@ func (p *Parser) ADDLStatment(code *opcode.Opcode) *opcode.Opcode {
 		}
 		p.nextToken()
 		if !p.peekTokenIs(token.REGISTER) {
-			p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q", p.peekToken.Literal))
+			p.registerError(p.peekToken, code.Token)
 			return nil
 		}
 		p.nextToken()
@@ -1074,22 +1224,22 @@ func (p *Parser) ADDLStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) SUBLStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	r1 := p.curToken.Literal
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.REGISTER) &&
 		!p.peekTokenIs(token.LABEL) && !p.peekTokenIs(token.HEX) &&
-		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・レジスタ・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeOperand, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -1099,12 +1249,12 @@ func (p *Parser) SUBLStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
@@ -1113,20 +1263,20 @@ func (p *Parser) SUBLStatment(code *opcode.Opcode) *opcode.Opcode {
 		code.Op = 0x27
 		code.Length = 1
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
 		if !p.peekTokenIs(token.REGISTER) {
-			p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q", p.peekToken.Literal))
+			p.registerError(p.peekToken, code.Token)
 			return nil
 		}
 		p.nextToken()
@@ -1155,22 +1305,22 @@ func (p *Parser) SUBLStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) ANDStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	r1 := p.curToken.Literal
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.REGISTER) &&
 		!p.peekTokenIs(token.LABEL) && !p.peekTokenIs(token.HEX) &&
-		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・レジスタ・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeOperand, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -1178,12 +1328,12 @@ func (p *Parser) ANDStatment(code *opcode.Opcode) *opcode.Opcode {
 	code.Length = 2
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
@@ -1192,18 +1342,22 @@ func (p *Parser) ANDStatment(code *opcode.Opcode) *opcode.Opcode {
 		code.Op = 0x34
 		code.Length = 1
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	case token.HEX:
@@ -1230,22 +1384,22 @@ func (p *Parser) ANDStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) ORStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	r1 := p.curToken.Literal
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.REGISTER) &&
 		!p.peekTokenIs(token.LABEL) && !p.peekTokenIs(token.HEX) &&
-		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -1255,12 +1409,12 @@ func (p *Parser) ORStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
@@ -1269,18 +1423,22 @@ func (p *Parser) ORStatment(code *opcode.Opcode) *opcode.Opcode {
 		code.Op = 0x35
 		code.Length = 1
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	case token.HEX:
@@ -1307,22 +1465,22 @@ func (p *Parser) ORStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) XORStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	r1 := p.curToken.Literal
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.REGISTER) &&
 		!p.peekTokenIs(token.LABEL) && !p.peekTokenIs(token.HEX) &&
-		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・レジスタ・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeOperand, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -1330,12 +1488,12 @@ func (p *Parser) XORStatment(code *opcode.Opcode) *opcode.Opcode {
 	code.Length = 2
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
@@ -1344,18 +1502,22 @@ func (p *Parser) XORStatment(code *opcode.Opcode) *opcode.Opcode {
 		code.Op = 0x36
 		code.Length = 1
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	case token.HEX:
@@ -1382,22 +1544,22 @@ func (p *Parser) XORStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) CPAStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	r1 := p.curToken.Literal
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.REGISTER) &&
 		!p.peekTokenIs(token.LABEL) && !p.peekTokenIs(token.HEX) &&
-		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・レジスタ・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeOperand, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -1405,12 +1567,12 @@ func (p *Parser) CPAStatment(code *opcode.Opcode) *opcode.Opcode {
 	code.Length = 2
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
@@ -1419,18 +1581,22 @@ func (p *Parser) CPAStatment(code *opcode.Opcode) *opcode.Opcode {
 		code.Op = 0x44
 		code.Length = 1
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	case token.HEX:
@@ -1457,22 +1623,22 @@ func (p *Parser) CPAStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) CPLStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	r1 := p.curToken.Literal
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(code.Token.Line, fmt.Sprintf("%s %s の後にカンマがありません。", code.Token.Literal, r1))
+		p.parserError(CodeComma, p.curToken, r1, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.REGISTER) &&
 		!p.peekTokenIs(token.LABEL) && !p.peekTokenIs(token.HEX) &&
-		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・レジスタ・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeOperand, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -1482,12 +1648,12 @@ func (p *Parser) CPLStatment(code *opcode.Opcode) *opcode.Opcode {
 
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
@@ -1496,18 +1662,22 @@ func (p *Parser) CPLStatment(code *opcode.Opcode) *opcode.Opcode {
 		code.Op = 0x45
 		code.Length = 1
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	case token.HEX:
@@ -1532,20 +1702,20 @@ func (p *Parser) CPLStatment(code *opcode.Opcode) *opcode.Opcode {
 // SLA r, adr [,x]	;
 func (p *Parser) SLAStatment(code *opcode.Opcode) *opcode.Opcode {
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%qがありません。対象 : %q\n", ",", p.peekToken.Literal))
+		p.parserError(CodeComma, p.curToken, p.curToken.Literal, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -1553,28 +1723,32 @@ func (p *Parser) SLAStatment(code *opcode.Opcode) *opcode.Opcode {
 	code.Length = 2
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
 		}
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	case token.HEX:
@@ -1598,20 +1772,20 @@ func (p *Parser) SLAStatment(code *opcode.Opcode) *opcode.Opcode {
 // SRA r, adr [,x]	;
 func (p *Parser) SRAStatment(code *opcode.Opcode) *opcode.Opcode {
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%qがありません。対象 : %q\n", ",", p.peekToken.Literal))
+		p.parserError(CodeComma, p.curToken, p.curToken.Literal, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -1619,29 +1793,33 @@ func (p *Parser) SRAStatment(code *opcode.Opcode) *opcode.Opcode {
 	code.Length = 2
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
 		}
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	case token.HEX:
@@ -1665,20 +1843,20 @@ func (p *Parser) SRAStatment(code *opcode.Opcode) *opcode.Opcode {
 // SLL r, adr [,x]	;
 func (p *Parser) SLLStatment(code *opcode.Opcode) *opcode.Opcode {
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%qがありません。対象 : %q\n", ",", p.peekToken.Literal))
+		p.parserError(CodeComma, p.curToken, p.curToken.Literal, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -1686,28 +1864,32 @@ func (p *Parser) SLLStatment(code *opcode.Opcode) *opcode.Opcode {
 	code.Length = 2
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
 		}
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	case token.HEX:
@@ -1731,20 +1913,20 @@ func (p *Parser) SLLStatment(code *opcode.Opcode) *opcode.Opcode {
 // SRL r, adr [,x]	;
 func (p *Parser) SRLStatment(code *opcode.Opcode) *opcode.Opcode {
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	// Next Token is ','
 	if !p.peekTokenIs(token.COMMA) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%qがありません。対象 : %q\n", ",", p.peekToken.Literal))
+		p.parserError(CodeComma, p.curToken, p.curToken.Literal, code.Token.Literal)
 		return nil
 	}
 	p.nextToken()
 	// Next Token is 'INT' or register or Label
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
@@ -1752,28 +1934,32 @@ func (p *Parser) SRLStatment(code *opcode.Opcode) *opcode.Opcode {
 	code.Length = 2
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 		code, err = p.indexRegisterParse(code)
 		if err != nil {
 			return nil
 		}
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 		if !p.peekTokenIs(token.COMMA) {
 			code.Code |= uint16(code.Op) << 8
 			return code
 		}
 		p.nextToken()
+		if !p.peekTokenIs(token.REGISTER) {
+			p.registerError(p.peekToken, code.Token)
+			return nil
+		}
 		p.nextToken()
 		code.Code |= uint16(registerNumber[p.curToken.Literal])
 	case token.HEX:
@@ -1798,39 +1984,39 @@ func (p *Parser) SRLStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) JMIStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x61, Code: 0x6100, Length: 2, Label: code.Label, Token: code.Token}
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 	case token.HEX:
 		addr, err := p.hexToAddress(p.curToken)
 		if err != nil {
 			return nil
 		}
 		code.Addr = uint16(addr)
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 	}
 	if !p.peekTokenIs(token.COMMA) {
 		return code
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q\n", p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -1843,39 +2029,39 @@ func (p *Parser) JMIStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) JNZStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x62, Code: 0x6200, Length: 2, Label: code.Label, Token: code.Token}
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 	case token.HEX:
 		addr, err := p.hexToAddress(p.curToken)
 		if err != nil {
 			return nil
 		}
 		code.Addr = uint16(addr)
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 	}
 	if !p.peekTokenIs(token.COMMA) {
 		return code
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q\n", p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -1888,39 +2074,39 @@ func (p *Parser) JNZStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) JZEStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x63, Code: 0x6300, Length: 2, Label: code.Label, Token: code.Token}
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 	case token.HEX:
 		addr, err := p.hexToAddress(p.curToken)
 		if err != nil {
 			return nil
 		}
 		code.Addr = uint16(addr)
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 	}
 	if !p.peekTokenIs(token.COMMA) {
 		return code
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q\n", p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -1933,39 +2119,39 @@ func (p *Parser) JZEStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) JUMPStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x64, Code: 0x6400, Length: 2, Label: code.Label, Token: code.Token}
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 	case token.HEX:
 		addr, err := p.hexToAddress(p.curToken)
 		if err != nil {
 			return nil
 		}
 		code.Addr = uint16(addr)
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 	}
 	if !p.peekTokenIs(token.COMMA) {
 		return code
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q\n", p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -1978,39 +2164,39 @@ func (p *Parser) JUMPStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) JPLStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x65, Code: 0x6500, Length: 2, Label: code.Label, Token: code.Token}
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 	case token.HEX:
 		addr, err := p.hexToAddress(p.curToken)
 		if err != nil {
 			return nil
 		}
 		code.Addr = uint16(addr)
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 	}
 	if !p.peekTokenIs(token.COMMA) {
 		return code
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q\n", p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -2023,39 +2209,39 @@ func (p *Parser) JPLStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) JOVStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x66, Code: 0x6600, Length: 2, Label: code.Label, Token: code.Token}
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 	case token.HEX:
 		addr, err := p.hexToAddress(p.curToken)
 		if err != nil {
 			return nil
 		}
 		code.Addr = uint16(addr)
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 	}
 	if !p.peekTokenIs(token.COMMA) {
 		return code
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q\n", p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -2068,39 +2254,39 @@ func (p *Parser) JOVStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) PUSHStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x70, Code: 0x7000, Length: 2, Label: code.Label, Token: code.Token}
 	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.LABEL) &&
-		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("数値・ラベルではありません。対象 : %q\n", p.peekToken.Literal))
+		!p.peekTokenIs(token.HEX) && !p.peekTokenIs(token.EQINT) && !p.peekTokenIs(token.EQHEX) && !p.peekTokenIs(token.EQSTRING) {
+		p.parserError(CodeNumberOrLabel, p.peekToken)
 		return nil
 	}
 	p.nextToken()
 	switch p.curToken.Type {
 	case token.INT:
-		addr, err := strconv.ParseInt(p.curToken.Literal, 0, 16)
+		addr, err := parseValue(p.curToken.Literal)
 		if err != nil {
-			p.parserError(p.curToken.Line, fmt.Sprintf("数値が適正ではありません。対象 : %q\n", p.curToken.Literal))
+			p.parserError(CodeInvalidNumber, p.curToken)
 			return nil
 		}
-		code.Addr = uint16(addr)
+		code.Addr = addr
 	case token.HEX:
 		addr, err := p.hexToAddress(p.curToken)
 		if err != nil {
 			return nil
 		}
 		code.Addr = uint16(addr)
-	case token.LABEL, token.EQINT, token.EQHEX:
+	case token.LABEL, token.EQINT, token.EQHEX, token.EQSTRING:
 		if token.LABEL != p.curToken.Type {
 			if p.symbolTable.LiteralDefine(p.curToken.Literal, 0x000) {
 				p.LiteralDC = append(p.LiteralDC, p.curToken)
 			}
 		}
-		code.AddrLabel = p.curToken.Literal
+		code.AddrLabel, code.AddrToken = p.curToken.Literal, p.curToken
 	}
 	if !p.peekTokenIs(token.COMMA) {
 		return code
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q\n", p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -2113,7 +2299,7 @@ func (p *Parser) PUSHStatment(code *opcode.Opcode) *opcode.Opcode {
 func (p *Parser) POPStatment(code *opcode.Opcode) *opcode.Opcode {
 	code = &opcode.Opcode{Op: 0x71, Code: 0x7100, Length: 1, Label: code.Label, Token: code.Token}
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil
 	}
 	p.nextToken()
@@ -2123,16 +2309,29 @@ func (p *Parser) POPStatment(code *opcode.Opcode) *opcode.Opcode {
 
 // hexToAddress #1000 → 4096(10)
 func (p *Parser) hexToAddress(tok token.Token) (uint16, error) {
-	if len(tok.Literal) != 5 {
-		p.parserError(tok.Line, fmt.Sprintf("16進数数値が適正ではありません。\n#0000~#FFFFまで使用できます対象 : %q", tok.Literal))
-		return 0, fmt.Errorf("16進数数値が適正ではありません。\n#0000~#FFFFまで使用できます対象 : %q", tok.Literal)
-	}
-	address, err := strconv.ParseUint(strings.Replace(tok.Literal, "#", "", 1), 16, 16)
+	address, err := parseValue(tok.Literal)
 	if err != nil {
-		p.parserError(tok.Line, fmt.Sprintf("16進数数値が適正ではありません。\n#0000~#FFFFまで使用できます対象 : %q", tok.Literal))
+		p.parserError(CodeHex, tok)
 		return 0, err
 	}
-	return uint16(address), nil
+	return address, nil
+}
+
+// parseValue 数値の Token の値。命令・DC・DS・リテラル・EQU・式で共通
+// 10進数は -32768〜65535 (先頭の 0 も10進数)、16進数は # と4桁
+func parseValue(s string) (uint16, error) {
+	if strings.HasPrefix(s, "#") {
+		if len(s) != 5 {
+			return 0, NewError(CodeHex, s)
+		}
+		v, err := strconv.ParseUint(s[1:], 16, 16)
+		return uint16(v), err
+	}
+	v, err := strconv.ParseInt(s, 10, 32)
+	if err == nil && (v < -32768 || v > 65535) {
+		err = fmt.Errorf("%d : 範囲外です", v)
+	}
+	return uint16(v), err
 }
 
 // indexRegisterParse
@@ -2143,7 +2342,7 @@ func (p *Parser) indexRegisterParse(code *opcode.Opcode) (*opcode.Opcode, error)
 	}
 	p.nextToken()
 	if !p.peekTokenIs(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("レジスタではありません。対象 : %q", p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil, fmt.Errorf("Register Error")
 	}
 	p.nextToken()
@@ -2175,13 +2374,13 @@ func (p *Parser) stringToAddress(code *opcode.Opcode, str string) uint16 {
 }
 func (p *Parser) checkRegister(code *opcode.Opcode) (*opcode.Opcode, error) {
 	if !p.expectPeek(token.REGISTER) {
-		p.parserError(p.peekToken.Line, fmt.Sprintf("%s %q \n%qはレジスタではありません。", code.Token.Literal, p.peekToken.Literal, p.peekToken.Literal))
+		p.registerError(p.peekToken, code.Token)
 		return nil, fmt.Errorf("non Register")
 	}
 	code.Code |= uint16(registerNumber[p.curToken.Literal]) << 4
 	//GR0 check
 	if registerNumber[p.curToken.Literal] == 0x00 {
-		p.parserWarning(p.curToken.Line, fmt.Sprintf("%qが使用されています", p.curToken.Literal))
+		p.parserWarning(CodeGR0, p.curToken)
 	}
 	return code, nil
 }
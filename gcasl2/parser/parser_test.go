@@ -0,0 +1,525 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+)
+
+// assemble src をアセンブルし、語列を返す
+func assemble(src string) ([]uint16, *Parser, error) {
+	p := New(lexer.New(src))
+	code, err := p.Assemble()
+	var words []uint16
+	for _, op := range code {
+		words = append(words, op.Words()...)
+	}
+	return words, p, err
+}
+
+// errorCodes "行:コード" の一覧
+func errorCodes(p *Parser) []string {
+	var codes []string
+	for _, e := range p.Errors() {
+		codes = append(codes, fmt.Sprintf("%d:%s", e.Line, e.Code))
+	}
+	return codes
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"register", "MAIN\tSTART\n\tLD\tGR9,X\n\tLD\tGR1,X\n\tRET\nX\tDS\t1\n\tEND\n", []string{"2:E0103"}},
+		{"multiple", "MAIN\tSTART\n\tLD\tGR9,X\n\tLD\tGR1,X\n\tFOO\tGR1\n\tST\tGR1,X,GR8\n\tRET\nX\tDS\t1\n\tEND\n", []string{"2:E0103", "4:E0003", "5:E0103"}},
+		{"comma", "MAIN\tSTART\n\tLD\tGR1 X\n\tADDA\tGR1,GR2\n\tRET\nX\tDS\t1\n\tEND\n", []string{"2:E0104"}},
+		// 行末でオペランドが足りないエラーは次の行ではなくその行のエラー
+		{"missing operand", "MAIN\tSTART\nX\tDS\t1\nX\tDC\t2\n\tLD\tGR1,\n\tRET\n\tLD\tGR1,X,\n\tEND\n", []string{"3:E0001", "4:E0105", "6:E0103"}},
+		{"number", "MAIN\tSTART\n\tDC\t#GG\n\tDS\t-1\n\tDS\t70000\n\tDC\t70000\n\tDC\t1,-32769\n\tLD\tGR1,=70000\n\tRET\n\tEND\n", []string{"2:E0101", "3:E0102", "4:E0102", "5:E0102", "6:E0102", "7:E0102"}},
+		{"after error", "MAIN\tSTART\n\tLD\tGR9,GR1 GR2 GR3\n\tRET\n\tEND\n", []string{"2:E0103"}},
+	}
+	for _, tt := range tests {
+		_, p, err := assemble(tt.src)
+		if err == nil {
+			t.Errorf("%s : エラーになりません", tt.name)
+			continue
+		}
+		if got := errorCodes(p); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMissingOperandColumn(t *testing.T) {
+	_, p, _ := assemble("MAIN\tSTART\n\tLD\tGR1,\n\tRET\n\tEND\n")
+	if errs := p.Errors(); len(errs) != 1 || errs[0].Line != 2 || errs[0].Column != 9 {
+		t.Fatalf("%+v", errs)
+	}
+}
+
+// TestNextLineLabel 行末でオペランドが足りないとき、次の行のラベルをオペランドにしない
+func TestNextLineLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"ld", "MAIN\tSTART\n\tLD\tGR1,\nY\tDS\t1\n\tRET\n\tEND\n", []string{"2:E0105"}},
+		{"lad", "MAIN\tSTART\n\tLAD\tGR1,\nY\tDS\t1\n\tRET\n\tEND\n", []string{"2:E0101"}},
+		{"adda", "MAIN\tSTART\n\tADDA\tGR1,\nFOO\tGR1\nY\tDS\t1\n\tRET\n\tEND\n", []string{"2:E0105", "3:E0003"}},
+		{"index", "MAIN\tSTART\n\tLD\tGR1,Y,\nY\tDS\t1\n\tRET\n\tEND\n", []string{"2:E0103"}},
+		{"register", "MAIN\tSTART\n\tST\nY\tDS\t1\n\tRET\n\tEND\n", []string{"2:E0103"}},
+		{"jump", "MAIN\tSTART\n\tJUMP\nY\tDS\t1\n\tRET\n\tEND\n", []string{"2:E0101"}},
+		{"dc", "MAIN\tSTART\n\tDC\t1,\nY\tDS\t1\n\tRET\n\tEND\n", []string{"2:E0101"}},
+		{"ds", "MAIN\tSTART\n\tDS\nY\tDS\t1\n\tRET\n\tEND\n", []string{"2:E0106"}},
+	}
+	for _, tt := range tests {
+		_, p, _ := assemble(tt.src)
+		if got := errorCodes(p); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.want)
+		}
+		// 次の行のラベルは定義される
+		if _, ok := p.SymbolTable().ResolveIn(1, "Y"); !ok {
+			t.Errorf("%s : Y が定義されていません", tt.name)
+		}
+		for _, e := range p.Errors() {
+			if e.Line == 2 && e.Column == 0 {
+				t.Errorf("%s : %+v", tt.name, e)
+			}
+		}
+	}
+}
+
+// TestErrorRecoveryAddress エラー行を読み飛ばしても後の行の番地はずれない
+func TestErrorRecoveryAddress(t *testing.T) {
+	_, p, _ := assemble("MAIN\tSTART\n\tLD\tGR1,X\n\tLD\tGR1,X,GR9\n\tST\tGR1,X\nX\tDS\t1\n\tEND\n")
+	if got := errorCodes(p); !reflect.DeepEqual(got, []string{"3:E0103"}) {
+		t.Fatal(got)
+	}
+	if sy, ok := p.SymbolTable().ResolveIn(1, "X"); !ok || sy.Address != 5 {
+		t.Fatalf("X #%04X %v", sy.Address, ok)
+	}
+}
+
+const linked = `MAIN	START
+	CALL	SUB
+	LD	GR1,X
+	RET
+X	DC	1
+	END
+SUB	START
+	LD	GR2,X
+	RET
+X	DC	2
+	END
+`
+
+func TestScope(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"linked", linked, nil},
+		{"local", linked + "OTHER\tSTART\n\tLD\tGR1,MAIN\n\tLD\tGR1,X\n\tRET\n\tEND\n", []string{"14:E0002"}},
+		{"duplicate start", linked + "SUB\tSTART\n\tRET\n\tEND\n", []string{"12:E0001"}},
+		{"missing end", "MAIN\tSTART\n\tRET\nSUB\tSTART\n\tRET\n\tEND\n", []string{"3:E0006"}},
+		{"end without start", "MAIN\tSTART\n\tRET\n\tEND\n\tEND\n", []string{"4:E0008"}},
+		{"start label", "\tSTART\n\tRET\n\tEND\n", []string{"1:E0007"}},
+		{"implicit", "\tLD\tGR1,GR2\n\tRET\n", nil},
+		{"implicit then start", "\tCALL\tSUB\n\tRET\nSUB\tSTART\n\tRET\n\tEND\n", nil},
+	}
+	for _, tt := range tests {
+		_, p, _ := assemble(tt.src)
+		if got := errorCodes(p); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLinkedAddress(t *testing.T) {
+	words, _, err := assemble(linked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0:MAIN 1:CALL 3:LD 5:RET 6:X 7:END 8:SUB 9:LD 11:RET 12:X 13:END
+	want := map[int]uint16{2: 8, 4: 6, 10: 12}
+	for i, w := range want {
+		if words[i] != w {
+			t.Errorf("%d番地 #%04X, want #%04X", i, words[i], w)
+		}
+	}
+}
+
+func TestEntry(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		entry uint16
+		ok    bool
+		err   []string
+	}{
+		{"start", "MAIN\tSTART\n\tRET\n\tEND\n", 0, true, nil},
+		{"operand", "MAIN\tSTART\tBEGIN\nX\tDC\t1\nBEGIN\tLD\tGR1,X\n\tRET\n\tEND\n", 2, true, nil},
+		{"first program", linked + "THIRD\tSTART\tL\nL\tRET\n\tEND\n", 0, true, nil},
+		{"implicit program", "\tRET\nSUB\tSTART\tL\n\tDC\t0\nL\tRET\n\tEND\n", 3, true, nil},
+		{"undefined", "MAIN\tSTART\tNONE\n\tRET\n\tEND\n", 0, true, []string{"1:E0002"}},
+		// オペランドは同じプログラムのラベル
+		{"other program", linked + "THIRD\tSTART\tSUB\n\tRET\n\tEND\n", 0, true, []string{"12:E0002"}},
+		{"next line", "MAIN\tSTART\nL\tRET\n\tEND\n", 0, true, nil},
+		{"no start", "", 0, false, nil},
+	}
+	for _, tt := range tests {
+		_, p, _ := assemble(tt.src)
+		if got := errorCodes(p); !reflect.DeepEqual(got, tt.err) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.err)
+			continue
+		}
+		if entry, ok := p.Entry(); entry != tt.entry || ok != tt.ok {
+			t.Errorf("%s : entry #%04X %v, want #%04X %v", tt.name, entry, ok, tt.entry, tt.ok)
+		}
+	}
+}
+
+func TestEntryLabel(t *testing.T) {
+	_, p, err := assemble(linked + "THIRD\tSTART\tL\n\tDC\t0\nL\tRET\n\tEND\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// START ラベルのアドレスもオペランドの番地になる
+	sy, ok := p.SymbolTable().Resolve("THIRD")
+	if !ok || sy.Address != 16 {
+		t.Fatalf("THIRD #%04X %v", sy.Address, ok)
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		words []uint16
+		err   []string
+	}{
+		{"int", "MAIN\tSTART\n\tLD\tGR1,=10\n\tRET\n\tEND\n", []uint16{0, 0x1010, 4, 0x8100, 10, 0}, nil},
+		{"hex", "MAIN\tSTART\n\tLD\tGR1,=#FFFF\n\tRET\n\tEND\n", []uint16{0, 0x1010, 4, 0x8100, 0xFFFF, 0}, nil},
+		{"string", "MAIN\tSTART\n\tLD\tGR1,='AB'\n\tRET\n\tEND\n", []uint16{0, 0x1010, 4, 0x8100, 'A', 'B', 0}, nil},
+		{"quote", "MAIN\tSTART\n\tLD\tGR1,='A''B'\n\tRET\n\tEND\n", []uint16{0, 0x1010, 4, 0x8100, 'A', '\'', 'B', 0}, nil},
+		// 同じリテラルは1つにまとめる
+		{"shared", "MAIN\tSTART\n\tLD\tGR1,='AB'\n\tLD\tGR2,='AB'\n\tRET\n\tEND\n", []uint16{0, 0x1010, 6, 0x1020, 6, 0x8100, 'A', 'B', 0}, nil},
+		// プログラムごとに配置する
+		{"programs", "A\tSTART\n\tLD\tGR1,=1\n\tRET\n\tEND\nB\tSTART\n\tLD\tGR1,=1\n\tRET\n\tEND\n", []uint16{0, 0x1010, 4, 0x8100, 1, 0, 0, 0x1010, 10, 0x8100, 1, 0}, nil},
+		// END のないプログラムのリテラルは次の START の前に配置する
+		{"implicit", "\tLD\tGR1,=1\n\tRET\nB\tSTART\n\tRET\n\tEND\n", []uint16{0x1010, 3, 0x8100, 1, 0, 0x8100, 0}, nil},
+		{"empty", "MAIN\tSTART\n\tLD\tGR1,=''\n\tRET\n\tEND\n", nil, []string{"2:E0108"}},
+	}
+	for _, tt := range tests {
+		words, p, _ := assemble(tt.src)
+		if got := errorCodes(p); !reflect.DeepEqual(got, tt.err) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.err)
+			continue
+		}
+		if tt.err == nil && !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("%s : %04X, want %04X", tt.name, words, tt.words)
+		}
+	}
+}
+
+// TestNumber 数値はどこに書いても同じ値 (先頭の 0 も10進数、16進数は4桁)
+func TestNumber(t *testing.T) {
+	// V の語の番地
+	forms := []struct {
+		name string
+		src  string
+		at   int
+	}{
+		{"operand", "MAIN\tSTART\n\tLAD\tGR1,V\n\tRET\n\tEND\n", 2},
+		{"expression", "MAIN\tSTART\n\tLAD\tGR1,V+0\n\tRET\n\tEND\n", 2},
+		{"equ", "N\tEQU\tV\nMAIN\tSTART\n\tLAD\tGR1,N\n\tRET\n\tEND\n", 2},
+		{"dc", "MAIN\tSTART\n\tDC\tV\n\tEND\n", 1},
+		{"literal", "MAIN\tSTART\n\tLD\tGR1,=V\n\tRET\n\tEND\n", 4},
+	}
+	tests := []struct {
+		v    string
+		want uint16
+		ok   bool
+	}{
+		{"10", 10, true},
+		{"010", 10, true},
+		{"#000A", 10, true},
+		{"65535", 0xFFFF, true},
+		{"70000", 0, false},
+		{"#FF", 0, false},
+		{"#0000F", 0, false},
+	}
+	for _, f := range forms {
+		for _, tt := range tests {
+			words, p, err := assemble(strings.Replace(f.src, "V", tt.v, 1))
+			if (err == nil) != tt.ok {
+				t.Errorf("%s %s : %v", f.name, tt.v, p.Errors())
+				continue
+			}
+			if tt.ok && words[f.at] != tt.want {
+				t.Errorf("%s %s : #%04X, want #%04X", f.name, tt.v, words[f.at], tt.want)
+			}
+		}
+	}
+	if words, _, err := assemble("MAIN\tSTART\n\tDS\t010\n\tEND\n"); err != nil || len(words) != 12 {
+		t.Errorf("DS 010 : %d words %v", len(words), err)
+	}
+}
+
+const wait = `WAIT	MACRO	&N
+L	SUBA	&N,=1
+	JNZ	L
+	MEND
+`
+
+func TestMacro(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		words []uint16
+		err   []string
+	}{
+		{"params", "ADD2\tMACRO\t&A,&B\n\tADDA\t&A,&B\n\tADDA\t&A,&B\n\tMEND\nMAIN\tSTART\n\tADD2\tGR1,GR2\n\tRET\n\tEND\n", []uint16{0, 0x2412, 0x2412, 0x8100, 0}, nil},
+		// 展開ごとにローカルラベルを別名にする
+		{"local label", wait + "MAIN\tSTART\n\tWAIT\tGR1\n\tWAIT\tGR2\n\tRET\n\tEND\n", []uint16{0, 0x2110, 10, 0x6200, 1, 0x2120, 10, 0x6200, 5, 0x8100, 1, 0}, nil},
+		{"call label", wait + "MAIN\tSTART\n\tJUMP\tX\nX\tWAIT\tGR1\n\tRET\n\tEND\n", []uint16{0, 0x6400, 3, 0x2110, 8, 0x6200, 3, 0x8100, 1, 0}, nil},
+		{"missing arg", "SET\tMACRO\t&R,&V\n\tLAD\t&R,&V\n\tMEND\nMAIN\tSTART\n\tSET\tGR1\n\tRET\n\tEND\n", nil, []string{"5:E0101"}},
+		{"nested call", wait + "TWICE\tMACRO\t&R\n\tWAIT\t&R\n\tWAIT\t&R\n\tMEND\nMAIN\tSTART\n\tTWICE\tGR3\n\tRET\n\tEND\n", []uint16{0, 0x2130, 10, 0x6200, 1, 0x2130, 10, 0x6200, 5, 0x8100, 1, 0}, nil},
+		{"nested definition", "DEF\tMACRO\nINNER\tMACRO\n\tRET\n\tMEND\n\tMEND\nMAIN\tSTART\n\tDEF\n\tINNER\n\tEND\n", []uint16{0, 0x8100, 0}, nil},
+		{"builtin", "MAIN\tSTART\n\tRPUSH\n\tRPOP\n\tRET\n\tEND\n", []uint16{0, 0x7001, 0, 0x7002, 0, 0x7003, 0, 0x7004, 0, 0x7005, 0, 0x7006, 0, 0x7007, 0, 0x7170, 0x7160, 0x7150, 0x7140, 0x7130, 0x7120, 0x7110, 0x8100, 0}, nil},
+		{"mend", "MAIN\tSTART\n\tMEND\n\tRET\n\tEND\n", nil, []string{"2:E0301"}},
+		{"no name", "\tMACRO\n\tMEND\n", nil, []string{"1:E0302"}},
+		{"reserved", "LD\tMACRO\n\tMEND\n", nil, []string{"1:E0303"}},
+		{"param comma", "M\tMACRO\t&A &B\n\tMEND\n", nil, []string{"1:E0304"}},
+		{"param name", "M\tMACRO\tA\n\tMEND\n", nil, []string{"1:E0305"}},
+		{"duplicate param", "M\tMACRO\t&A,&A\n\tMEND\n", nil, []string{"1:E0306"}},
+		{"unclosed", "M\tMACRO\n\tRET\n", nil, []string{"1:E0307"}},
+		{"not param", "M\tMACRO\t&A\n\tLD\t&A,&B\n\tMEND\n", nil, []string{"2:E0308"}},
+		{"recursive", "LOOP\tMACRO\n\tLOOP\n\tMEND\nMAIN\tSTART\n\tLOOP\n\tEND\n", nil, []string{"5:E0309"}},
+		{"args", wait + "MAIN\tSTART\n\tWAIT\tGR1,GR2\n\tEND\n", nil, []string{"6:E0310"}},
+	}
+	for _, tt := range tests {
+		words, p, _ := assemble(tt.src)
+		if got := errorCodes(p); !reflect.DeepEqual(got, tt.err) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.err)
+			continue
+		}
+		if tt.err == nil && !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("%s : %04X, want %04X", tt.name, words, tt.words)
+		}
+	}
+}
+
+// TestMacroRedefined 組み込みマクロの再定義は警告
+func TestMacroRedefined(t *testing.T) {
+	words, p, err := assemble("OUT\tMACRO\t&A,&B\n\tLD\t&A,&B\n\tMEND\nMAIN\tSTART\n\tOUT\tGR1,GR2\n\tRET\n\tEND\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := p.Warnings(); len(w) != 1 || w[0].Code != CodeMacroRedefined || w[0].Line != 1 {
+		t.Errorf("%+v", w)
+	}
+	if want := []uint16{0, 0x1412, 0x8100, 0}; !reflect.DeepEqual(words, want) {
+		t.Errorf("%04X, want %04X", words, want)
+	}
+}
+
+// debug DEBUG が 0 以外なら GR1 を 1、それ以外は 2 にする
+const debug = "MAIN\tSTART\n\tIF\tDEBUG\n\tLAD\tGR1,1\n\tELSE\n\tLAD\tGR1,2\n\tENDIF\n\tRET\n\tEND\n"
+
+func TestConstant(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		words []uint16
+		err   []string
+	}{
+		{"equ", "N\tEQU\t5\nMAIN\tSTART\n\tLAD\tGR1,N\n\tRET\n\tEND\n", []uint16{0, 0x1210, 5, 0x8100, 0}, nil},
+		{"hex", "N\tEQU\t#00FF\nMAIN\tSTART\n\tLAD\tGR1,N\n\tRET\n\tEND\n", []uint16{0, 0x1210, 0xFF, 0x8100, 0}, nil},
+		{"negative", "N\tEQU\t-1\nMAIN\tSTART\n\tDC\tN\n\tEND\n", []uint16{0, 0xFFFF, 0}, nil},
+		{"expression", "N\tEQU\t5\nM\tEQU\tN*2+1\nMAIN\tSTART\n\tLAD\tGR1,M\n\tRET\n\tEND\n", []uint16{0, 0x1210, 11, 0x8100, 0}, nil},
+		{"if", "DEBUG\tEQU\t1\n" + debug, []uint16{0, 0x1210, 1, 0x8100, 0}, nil},
+		{"else", "DEBUG\tEQU\t0\n" + debug, []uint16{0, 0x1210, 2, 0x8100, 0}, nil},
+		// 無効な IF の中の IF・ELSE はアセンブルしない
+		{"nested", "A\tEQU\t0\nB\tEQU\t0\nMAIN\tSTART\n\tIF\tA\n\tIF\tB\n\tLAD\tGR1,1\n\tELSE\n\tLAD\tGR1,2\n\tENDIF\n\tENDIF\n\tRET\n\tEND\n", []uint16{0, 0x8100, 0}, nil},
+		{"skipped errors", "MAIN\tSTART\n\tIF\t0\n\tLD\tGR9,X\n\tFOO\n\tENDIF\n\tRET\n\tEND\n", []uint16{0, 0x8100, 0}, nil},
+		{"no label", "\tEQU\t1\n", nil, []string{"1:E0201"}},
+		{"value", "N\tEQU\t'A'\n", nil, []string{"1:E0202"}},
+		// 番地は定数にできない
+		{"address", "MAIN\tSTART\nX\tDS\t1\nN\tEQU\tX\n\tEND\n", nil, []string{"3:E0209"}},
+		{"directive label", "MAIN\tSTART\nL\tIF\t1\n\tENDIF\n\tRET\n\tEND\n", nil, []string{"2:E0203"}},
+		{"else", "MAIN\tSTART\n\tELSE\n\tRET\n\tEND\n", nil, []string{"2:E0204"}},
+		{"second else", "MAIN\tSTART\n\tIF\t1\n\tELSE\n\tELSE\n\tENDIF\n\tEND\n", nil, []string{"4:E0204"}},
+		{"endif", "MAIN\tSTART\n\tENDIF\n\tRET\n\tEND\n", nil, []string{"2:E0205"}},
+		{"unclosed", "MAIN\tSTART\n\tIF\t1\n\tRET\n\tEND\n", nil, []string{"2:E0206"}},
+		{"undefined", debug, nil, []string{"2:E0209"}},
+		{"missing value", "N\tEQU\nMAIN\tSTART\n\tIF\n\tENDIF\n\tRET\n\tEND\n", nil, []string{"1:E0210", "3:E0210"}},
+		{"duplicate", "N\tEQU\t1\nN\tEQU\t2\n", nil, []string{"2:E0001"}},
+	}
+	for _, tt := range tests {
+		words, p, _ := assemble(tt.src)
+		if got := errorCodes(p); !reflect.DeepEqual(got, tt.err) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.err)
+			continue
+		}
+		if tt.err == nil && !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("%s : %04X, want %04X", tt.name, words, tt.words)
+		}
+	}
+}
+
+func TestDefineConstants(t *testing.T) {
+	tests := []struct {
+		defs string
+		src  string
+		gr1  uint16
+		err  string
+	}{
+		{"DEBUG", debug, 1, ""},
+		{"DEBUG=0", debug, 2, ""},
+		{"X=1, DEBUG=#0000", debug, 2, ""},
+		// ソースの EQU より優先する
+		{"DEBUG=0", "DEBUG\tEQU\t1\n" + debug, 2, ""},
+		{"debug=1", debug, 0, CodeConstantName},
+		{"LD=1", debug, 0, CodeConstantName},
+		{"DEBUG=ABC", debug, 0, CodeConstantValue},
+		{"DEBUG=70000", debug, 0, CodeConstantValue},
+		{"DEBUG,DEBUG", debug, 0, CodeDuplicateLabel},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.src))
+		err := p.DefineConstants(tt.defs)
+		code, asmErr := p.Assemble()
+		if tt.err != "" {
+			if err == nil || asmErr == nil || p.Errors()[0].Code != tt.err {
+				t.Errorf("%s : %v %v, want %s", tt.defs, err, p.Errors(), tt.err)
+			}
+			continue
+		}
+		if err != nil || asmErr != nil {
+			t.Errorf("%s : %v %v", tt.defs, err, p.Errors())
+			continue
+		}
+		if gr1 := code[1].Words()[1]; gr1 != tt.gr1 {
+			t.Errorf("%s : GR1 %d, want %d", tt.defs, gr1, tt.gr1)
+		}
+	}
+}
+
+// sources INCLUDE で取り込むソース
+var sources = map[string]string{
+	"set.cas":   "\tLAD\tGR1,1\n\tLAD\tGR2,2\n",
+	"outer.cas": "\tLAD\tGR3,3\n\tINCLUDE\t'set.cas'\n",
+	"cycle.cas": "\tINCLUDE\t'loop.cas'\n",
+	"loop.cas":  "\tRET\n\tINCLUDE\t'cycle.cas'\n",
+	"error.cas": "\tRET\n\tLD\tGR9,X\n",
+	"deep.cas":  "\tINCLUDE\t'error.cas'\n",
+	"macro.cas": wait,
+}
+
+func includer(name string) (string, error) {
+	if src, ok := sources[name]; ok {
+		return src, nil
+	}
+	return "", fmt.Errorf("%q : ファイルが見つかりません。", name)
+}
+
+func TestInclude(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		words []uint16
+		err   []string
+	}{
+		{"include", "MAIN\tSTART\n\tINCLUDE\t'set.cas'\n\tRET\n\tEND\n", []uint16{0, 0x1210, 1, 0x1220, 2, 0x8100, 0}, nil},
+		{"nested", "MAIN\tSTART\n\tINCLUDE\t'outer.cas'\n\tRET\n\tEND\n", []uint16{0, 0x1230, 3, 0x1210, 1, 0x1220, 2, 0x8100, 0}, nil},
+		{"label", "MAIN\tSTART\n\tINCLUDE\t'set.cas'\nL\tRET\n\tJUMP\tL\n\tEND\n", []uint16{0, 0x1210, 1, 0x1220, 2, 0x8100, 0x6400, 5, 0}, nil},
+		{"macro", "\tINCLUDE\t'macro.cas'\nMAIN\tSTART\n\tWAIT\tGR1\n\tRET\n\tEND\n", []uint16{0, 0x2110, 6, 0x6200, 1, 0x8100, 1, 0}, nil},
+		{"cycle", "MAIN\tSTART\n\tINCLUDE\t'cycle.cas'\n\tEND\n", nil, []string{"2:E0402"}},
+		{"operand", "MAIN\tSTART\n\tINCLUDE\tX\n\tRET\n\tEND\n", nil, []string{"2:E0401"}},
+		{"empty operand", "MAIN\tSTART\n\tINCLUDE\t''\n\tRET\n\tEND\n", nil, []string{"2:E0401"}},
+		{"directive label", "MAIN\tSTART\nL\tINCLUDE\t'set.cas'\n\tRET\n\tEND\n", nil, []string{"2:E0203"}},
+		{"source", "MAIN\tSTART\n\tINCLUDE\t'none.cas'\n\tRET\n\tEND\n", nil, []string{"2:E0404"}},
+		{"error", "MAIN\tSTART\n\tINCLUDE\t'error.cas'\n\tRET\nX\tDS\t1\n\tEND\n", nil, []string{"2:E0103"}},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.src))
+		p.SetIncluder(includer)
+		code, _ := p.Assemble()
+		if got := errorCodes(p); !reflect.DeepEqual(got, tt.err) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.err)
+			continue
+		}
+		var words []uint16
+		for _, op := range code {
+			words = append(words, op.Words()...)
+		}
+		if tt.err == nil && !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("%s : %04X, want %04X", tt.name, words, tt.words)
+		}
+	}
+}
+
+// TestIncludePosition 取り込んだソースのエラーは INCLUDE の行と、ファイル内の位置
+func TestIncludePosition(t *testing.T) {
+	p := New(lexer.New("MAIN\tSTART\n\tINCLUDE\t'error.cas'\n\tRET\nX\tDS\t1\n\tEND\n"))
+	p.SetIncluder(includer)
+	p.Assemble()
+	errs := p.Errors()
+	if len(errs) != 1 || errs[0].Line != 2 || errs[0].Column != 10 || errs[0].EndColumn != 21 {
+		t.Fatalf("%+v", errs)
+	}
+	if want := []string{"error.cas:2:5"}; !reflect.DeepEqual(errs[0].Include, want) {
+		t.Errorf("%v, want %v", errs[0].Include, want)
+	}
+	p = New(lexer.New("MAIN\tSTART\n\tINCLUDE\t'deep.cas'\n\tRET\nX\tDS\t1\n\tEND\n"))
+	p.SetIncluder(includer)
+	p.Assemble()
+	if errs := p.Errors(); len(errs) != 1 || !reflect.DeepEqual(errs[0].Include, []string{"error.cas:2:5", "deep.cas:1:2"}) {
+		t.Errorf("%+v", errs)
+	}
+	// INCLUDE を無効にしたパーサ
+	_, p, _ = assemble("MAIN\tSTART\n\tINCLUDE\t'set.cas'\n\tRET\n\tEND\n")
+	if got := errorCodes(p); !reflect.DeepEqual(got, []string{"2:E0403"}) {
+		t.Errorf("%v", got)
+	}
+}
+
+// TestErrorColumn エラーの範囲 (行頭を 1 とするバイト位置)
+func TestErrorColumn(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		code   string
+		column int
+		end    int
+	}{
+		{"register", "\tLD\tGR9,X", CodeRegister, 5, 8},
+		// カンマがないのはレジスタの後
+		{"comma", "\tLD\tGR1 X", CodeComma, 5, 8},
+		{"shift comma", "\tSLA\tGR1 X", CodeComma, 6, 9},
+		{"unknown", "\tLD\tGR1,X\n\tFOO\tGR1", CodeUnknown, 6, 9},
+		{"undefined", "\tLD\tGR1,NONE", CodeUndefined, 9, 13},
+		{"expression", "\tLD\tGR1,BUF * 2 + 1\nBUF\tDS\t1", CodeExprProduct, 9, 20},
+		{"number", "\tDC\t70000", CodeInvalidNumber, 5, 10},
+		{"literal", "\tLD\tGR1,=-1", CodeOperand, 9, 10},
+		// リテラルの数値の誤りは END ではなくリテラルの位置
+		{"literal number", "\tLD\tGR1,=70000", CodeInvalidNumber, 9, 15},
+		{"literal hex", "\tLD\tGR1,=#0FFFF", CodeInvalidNumber, 9, 16},
+		{"operand", "\tLD\tGR1,", CodeOperand, 9, 9},
+	}
+	for _, tt := range tests {
+		_, p, _ := assemble("MAIN\tSTART\n" + tt.src + "\n\tRET\n\tEND\n")
+		errs := p.Errors()
+		if len(errs) == 0 || errs[0].Code != tt.code || errs[0].Column != tt.column || errs[0].EndColumn != tt.end {
+			t.Errorf("%s : %+v, want %s %d-%d", tt.name, errs, tt.code, tt.column, tt.end)
+		}
+	}
+}
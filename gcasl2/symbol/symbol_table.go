@@ -0,0 +1,157 @@
+package symbol
+
+import "sort"
+
+type Symbol struct {
+	Label   string
+	Index   int
+	Address uint16 //定数 (EQU) の場合は値
+	Scope   int    //定義されたプログラム (START〜END) の番号
+	Line    int    //定義された行
+	Kind    Kind
+}
+
+// Kind シンボルの種類
+type Kind string
+
+const (
+	KindLabel    = Kind("LABEL")    //番地を表すラベル
+	KindLiteral  = Kind("LITERAL")  //=10・=#FFFF・='ABC'
+	KindConstant = Kind("CONSTANT") //EQU で定義した定数 (再配置しない)
+)
+
+// scopedLabel プログラムごとのラベル
+type scopedLabel struct {
+	scope int
+	label string
+}
+
+type SymbolTable struct {
+	store          map[scopedLabel]Symbol
+	entries        map[string]Symbol //START ラベル (他のプログラムから参照できる)
+	scope          int
+	numDefinitions int
+}
+
+func NewSymbolTable() *SymbolTable {
+	s := make(map[scopedLabel]Symbol)
+	return &SymbolTable{store: s, entries: map[string]Symbol{}}
+}
+
+// BeginScope 新しいプログラムのスコープを開始する
+func (s *SymbolTable) BeginScope() int {
+	s.scope++
+	return s.scope
+}
+
+// Scope 現在のプログラムの番号 (最初の START より前は 0)
+func (s *SymbolTable) Scope() int {
+	return s.scope
+}
+
+func (s *SymbolTable) Define(label string, addr uint16) (Symbol, bool) {
+	return s.DefineLine(label, addr, 0)
+}
+
+// DefineLine line 行目で定義されたラベル
+func (s *SymbolTable) DefineLine(label string, addr uint16, line int) (Symbol, bool) {
+	return s.define(Symbol{Label: label, Address: addr, Scope: s.scope, Line: line, Kind: KindLabel})
+}
+
+// DefineConstant line 行目の EQU で定義された定数 (START より前なら全てのプログラムから参照できる)
+func (s *SymbolTable) DefineConstant(label string, value uint16, line int) (Symbol, bool) {
+	return s.define(Symbol{Label: label, Address: value, Scope: s.scope, Line: line, Kind: KindConstant})
+}
+
+func (s *SymbolTable) define(symbol Symbol) (Symbol, bool) {
+	key := scopedLabel{symbol.Scope, symbol.Label}
+	if val, ok := s.store[key]; ok {
+		return val, false
+	}
+	symbol.Index = s.numDefinitions
+	s.store[key] = symbol
+	s.numDefinitions++
+	return symbol, true
+}
+
+// Export 現在のスコープのラベルを START ラベルとして他のプログラムに公開する
+// 同名の START ラベルが既にあれば false
+func (s *SymbolTable) Export(label string) (Symbol, bool) {
+	sy, ok := s.store[scopedLabel{s.scope, label}]
+	if !ok {
+		return sy, false
+	}
+	if val, ok := s.entries[label]; ok {
+		return val, false
+	}
+	s.entries[label] = sy
+	return sy, true
+}
+
+// SetEntry START ラベルのアドレスを実行開始番地 (START のオペランド) にする
+func (s *SymbolTable) SetEntry(label string, addr uint16) {
+	key := scopedLabel{s.scope, label}
+	if obj, ok := s.store[key]; ok {
+		obj.Address = addr
+		s.store[key] = obj
+	}
+	if obj, ok := s.entries[label]; ok && obj.Scope == s.scope {
+		obj.Address = addr
+		s.entries[label] = obj
+	}
+}
+
+func (s *SymbolTable) LiteralDefine(label string, addr uint16) bool {
+	_, ok := s.define(Symbol{Label: label, Address: addr, Scope: s.scope, Kind: KindLiteral})
+	return ok
+}
+func (s *SymbolTable) LiteralAddressSet(label string, addr uint16) {
+	key := scopedLabel{s.scope, label}
+	obj, ok := s.store[key]
+	if !ok {
+		return
+	}
+	obj.Address = addr
+	s.store[key] = obj
+	return
+}
+
+// Resolve 現在のスコープ、START ラベル、START より前に定義した定数の順に探す
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	return s.ResolveIn(s.scope, name)
+}
+
+// ResolveIn scope のプログラムから参照した name
+func (s *SymbolTable) ResolveIn(scope int, name string) (Symbol, bool) {
+	if obj, ok := s.store[scopedLabel{scope, name}]; ok {
+		return obj, ok
+	}
+	if obj, ok := s.entries[name]; ok {
+		return obj, ok
+	}
+	obj, ok := s.store[scopedLabel{0, name}]
+	if !ok || obj.Kind != KindConstant {
+		return Symbol{}, false
+	}
+	return obj, true
+}
+
+// Symbols 定義順のシンボル一覧
+func (s *SymbolTable) Symbols() []Symbol {
+	symbols := make([]Symbol, 0, len(s.store))
+	for _, sy := range s.store {
+		symbols = append(symbols, sy)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Index < symbols[j].Index })
+	return symbols
+}
+
+// Entries 他のプログラムから参照できる START ラベルの一覧 (定義順)
+func (s *SymbolTable) Entries() []Symbol {
+	symbols := make([]Symbol, 0, len(s.entries))
+	for _, sy := range s.entries {
+		symbols = append(symbols, sy)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Index < symbols[j].Index })
+	return symbols
+}
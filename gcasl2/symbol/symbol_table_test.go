@@ -0,0 +1,75 @@
+package symbol
+
+import "testing"
+
+func TestResolveIn(t *testing.T) {
+	s := NewSymbolTable()
+	s.DefineConstant("N", 10, 1)
+	s.DefineLine("TOP", 0, 2) // START より前のラベルは定数ではないので参照できない
+	s.BeginScope()
+	s.DefineLine("MAIN", 0, 3)
+	s.Export("MAIN")
+	s.DefineLine("X", 5, 4)
+	s.BeginScope()
+	s.DefineLine("SUB", 10, 6)
+	s.Export("SUB")
+	s.DefineLine("X", 15, 7)
+	s.DefineConstant("N", 20, 8)
+
+	tests := []struct {
+		scope int
+		name  string
+		addr  uint16
+		ok    bool
+	}{
+		{1, "X", 5, true},
+		{2, "X", 15, true},
+		{1, "SUB", 10, true},
+		{2, "MAIN", 0, true},
+		{1, "N", 10, true},
+		{2, "N", 20, true},
+		{1, "TOP", 0, false},
+		{3, "X", 0, false},
+		{3, "SUB", 10, true},
+	}
+	for _, tt := range tests {
+		sy, ok := s.ResolveIn(tt.scope, tt.name)
+		if ok != tt.ok || sy.Address != tt.addr {
+			t.Errorf("%d %s : #%04X %v, want #%04X %v", tt.scope, tt.name, sy.Address, ok, tt.addr, tt.ok)
+		}
+	}
+	if sy, ok := s.Resolve("X"); !ok || sy.Address != 15 {
+		t.Errorf("Resolve X : #%04X %v", sy.Address, ok)
+	}
+}
+
+func TestDefine(t *testing.T) {
+	s := NewSymbolTable()
+	s.BeginScope()
+	if _, ok := s.DefineLine("A", 0, 1); !ok {
+		t.Fatal("A を定義できません")
+	}
+	if sy, ok := s.DefineLine("A", 3, 2); ok || sy.Line != 1 {
+		t.Fatalf("重複定義 %+v %v", sy, ok)
+	}
+	s.Export("A")
+	s.BeginScope()
+	s.DefineLine("A", 7, 3)
+	if _, ok := s.Export("A"); ok {
+		t.Fatal("同名の START ラベルを公開できます")
+	}
+	if _, ok := s.Export("B"); ok {
+		t.Fatal("未定義のラベルを公開できます")
+	}
+	s.SetEntry("A", 9)
+	if sy, _ := s.ResolveIn(1, "A"); sy.Address != 0 {
+		t.Fatalf("他のプログラムの START ラベルが変わりました #%04X", sy.Address)
+	}
+	var labels []string
+	for _, sy := range s.Symbols() {
+		labels = append(labels, sy.Label)
+	}
+	if len(labels) != 2 || len(s.Entries()) != 1 {
+		t.Fatalf("symbols %v entries %v", labels, s.Entries())
+	}
+}
@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 const (
@@ -14,6 +16,14 @@ const (
 	OUT       = "OUT"
 	RPUSH     = "RPUSH"
 	RPOP      = "RPOP"
+	MACRO     = "MACRO"
+	MEND      = "MEND"
+	PARAM     = "PARAM"
+	EQU       = "EQU"
+	IF        = "IF"
+	ELSE      = "ELSE"
+	ENDIF     = "ENDIF"
+	INCLUDE   = "INCLUDE"
 	EOF       = "EOF"
 	INT       = "INT"
 	EQINT     = "EQINT"
@@ -24,6 +34,12 @@ const (
 	SHARP     = "#"
 	COMMA     = ","
 	SEMICOLON = ":"
+	PLUS      = "+"
+	MINUS     = "-"
+	ASTERISK  = "*"
+	SLASH     = "/"
+	LPAREN    = "("
+	RPAREN    = ")"
 	LD        = "LD"
 	ST        = "ST"
 	LAD       = "LAD"
@@ -55,10 +71,41 @@ const (
 	ADLI      = "DC"
 )
 
+// Lexer.NextRawToken のみが返す空白・改行・コメント
+const (
+	WHITESPACE = "WHITESPACE"
+	NEWLINE    = "NEWLINE"
+	COMMENT    = "COMMENT"
+)
+
 type Token struct {
-	Type    TokenType `json:"-"`
-	Literal string
-	Line    int
+	Type      TokenType `json:"-"`
+	Literal   string
+	Line      int
+	Column    int      `json:",omitempty"` //行頭を 1 とするバイト位置
+	EndColumn int      `json:",omitempty"` //Token の次の文字の位置
+	Include   *Include `json:",omitempty"` //INCLUDE したファイルの Token の位置 (Line は INCLUDE の行)
+}
+
+// Include INCLUDE したファイル File の Line 行目 Column 文字目。Parent は INCLUDE 命令自体の位置 (入れ子のとき)
+type Include struct {
+	File   string
+	Line   int
+	Column int      `json:",omitempty"`
+	Parent *Include `json:",omitempty"`
+}
+
+// Stack 内側から file:line:column の一覧
+func (i *Include) Stack() []string {
+	var stack []string
+	for ; i != nil; i = i.Parent {
+		if i.Column == 0 {
+			stack = append(stack, fmt.Sprintf("%s:%d", i.File, i.Line))
+			continue
+		}
+		stack = append(stack, fmt.Sprintf("%s:%d:%d", i.File, i.Line, i.Column))
+	}
+	return stack
 }
 
 var caslLetter = map[byte]uint8{
@@ -88,50 +135,57 @@ var caslLetter = map[byte]uint8{
 	'|': 0x7C, '}': 0x7D, '~': 0x7E,
 }
 var keywords = map[string]TokenType{
-	"GR0":   REGISTER,
-	"GR1":   REGISTER,
-	"GR2":   REGISTER,
-	"GR3":   REGISTER,
-	"GR4":   REGISTER,
-	"GR5":   REGISTER,
-	"GR6":   REGISTER,
-	"GR7":   REGISTER,
-	"START": START,
-	"END":   END,
-	"DS":    DS,
-	"DC":    DC,
-	"IN":    IN,
-	"OUT":   OUT,
-	"RPUSH": RPUSH,
-	"RPOP":  RPOP,
-	"LD":    LD,
-	"ST":    ST,
-	"LAD":   LAD,
-	"ADDA":  ADDA,
-	"ADDL":  ADDL,
-	"SUBA":  SUBA,
-	"SUBL":  SUBL,
-	"AND":   AND,
-	"OR":    OR,
-	"XOR":   XOR,
-	"CPA":   CPA,
-	"CPL":   CPL,
-	"SLA":   SLA,
-	"SRA":   SRA,
-	"SLL":   SLL,
-	"SRL":   SRL,
-	"JPL":   JPL,
-	"JMI":   JMI,
-	"JNZ":   JNZ,
-	"JZE":   JZE,
-	"JOV":   JOV,
-	"JUMP":  JUMP,
-	"PUSH":  PUSH,
-	"POP":   POP,
-	"CALL":  CALL,
-	"RET":   RET,
-	"SVC":   SVC,
-	"NOP":   NOP,
+	"GR0":     REGISTER,
+	"GR1":     REGISTER,
+	"GR2":     REGISTER,
+	"GR3":     REGISTER,
+	"GR4":     REGISTER,
+	"GR5":     REGISTER,
+	"GR6":     REGISTER,
+	"GR7":     REGISTER,
+	"START":   START,
+	"END":     END,
+	"DS":      DS,
+	"DC":      DC,
+	"IN":      IN,
+	"OUT":     OUT,
+	"RPUSH":   RPUSH,
+	"RPOP":    RPOP,
+	"MACRO":   MACRO,
+	"MEND":    MEND,
+	"EQU":     EQU,
+	"IF":      IF,
+	"ELSE":    ELSE,
+	"ENDIF":   ENDIF,
+	"INCLUDE": INCLUDE,
+	"LD":      LD,
+	"ST":      ST,
+	"LAD":     LAD,
+	"ADDA":    ADDA,
+	"ADDL":    ADDL,
+	"SUBA":    SUBA,
+	"SUBL":    SUBL,
+	"AND":     AND,
+	"OR":      OR,
+	"XOR":     XOR,
+	"CPA":     CPA,
+	"CPL":     CPL,
+	"SLA":     SLA,
+	"SRA":     SRA,
+	"SLL":     SLL,
+	"SRL":     SRL,
+	"JPL":     JPL,
+	"JMI":     JMI,
+	"JNZ":     JNZ,
+	"JZE":     JZE,
+	"JOV":     JOV,
+	"JUMP":    JUMP,
+	"PUSH":    PUSH,
+	"POP":     POP,
+	"CALL":    CALL,
+	"RET":     RET,
+	"SVC":     SVC,
+	"NOP":     NOP,
 }
 
 func LookupLetter(ch byte) (uint8, bool) {
@@ -0,0 +1,103 @@
+// Package include `INCLUDE 'name'` で取り込むソースを探す (parser.Includer)
+//
+// Path はディレクトリの一覧から、Snippets はサーバに登録した名前付きのソースから探す
+// どちらも登録した範囲の外 (絶対パス・..・ディレクトリ外へのシンボリックリンク) は参照できない
+package include
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MaxSize 取り込めるソースの大きさ (バイト)
+const MaxSize = 1 << 20
+
+// Path 順に探すディレクトリ
+type Path []string
+
+// ParsePath os.PathListSeparator またはカンマ区切りのディレクトリ (空の要素は除く)
+func ParsePath(s string) Path {
+	var p Path
+	for _, dir := range strings.FieldsFunc(s, func(r rune) bool { return r == os.PathListSeparator || r == ',' }) {
+		p = append(p, dir)
+	}
+	return p
+}
+
+// Source 最初に見つかった name のソース
+func (p Path) Source(name string) (string, error) {
+	if err := check(name); err != nil {
+		return "", err
+	}
+	for _, dir := range p {
+		root, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		file, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(root, file); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("%q : 検索パスの外のファイルは参照できません。", name)
+		}
+		info, err := os.Stat(file)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if info.Size() > MaxSize {
+			return "", fmt.Errorf("%q : ファイルが大きすぎます。", name)
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	return "", fmt.Errorf("%q : ファイルが見つかりません。", name)
+}
+
+// Snippets 名前付きのソース (名前は拡張子 .cas を除いたもの)
+type Snippets map[string]string
+
+// LoadSnippets dir の *.cas を読み込む
+func LoadSnippets(dir string) (Snippets, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.cas"))
+	if err != nil {
+		return nil, err
+	}
+	s := Snippets{}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		s[strings.TrimSuffix(filepath.Base(file), ".cas")] = string(b)
+	}
+	return s, nil
+}
+
+// Source name (拡張子 .cas は省略できる) のソース
+func (s Snippets) Source(name string) (string, error) {
+	if src, ok := s[strings.TrimSuffix(name, ".cas")]; ok {
+		return src, nil
+	}
+	return "", fmt.Errorf("%q : 登録されていません。", name)
+}
+
+// check name は / 区切りの相対パスで、.. を含まない
+func check(name string) error {
+	if name == "" || path.IsAbs(name) || filepath.IsAbs(name) || strings.ContainsAny(name, "\\\x00") {
+		return fmt.Errorf("%q : ファイル名が適正ではありません。", name)
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return fmt.Errorf("%q : ファイル名が適正ではありません。", name)
+		}
+	}
+	return nil
+}
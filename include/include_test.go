@@ -0,0 +1,103 @@
+package include
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	sep := string(os.PathListSeparator)
+	tests := []struct {
+		s    string
+		want Path
+	}{
+		{"", nil},
+		{"lib", Path{"lib"}},
+		{"lib" + sep + "share,opt", Path{"lib", "share", "opt"}},
+		{sep + "lib,," + sep, Path{"lib"}},
+	}
+	for _, tt := range tests {
+		if got := ParsePath(tt.s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q : %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestPathSource(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, src string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("lib/io.cas", "lib io")
+	write("lib/sub/mult.cas", "lib mult")
+	write("share/io.cas", "share io")
+	write("share/only.cas", "share only")
+	write("secret.cas", "secret")
+	write("lib/big.cas", strings.Repeat(";", MaxSize+1))
+	os.Mkdir(filepath.Join(root, "lib", "dir.cas"), 0755)
+	os.Symlink(filepath.Join(root, "secret.cas"), filepath.Join(root, "lib", "link.cas"))
+	os.Symlink(filepath.Join(root, "lib", "io.cas"), filepath.Join(root, "lib", "inner.cas"))
+
+	p := Path{filepath.Join(root, "none"), filepath.Join(root, "lib"), filepath.Join(root, "share")}
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{"io.cas", "lib io", ""},
+		{"only.cas", "share only", ""},
+		{"sub/mult.cas", "lib mult", ""},
+		{"inner.cas", "lib io", ""},
+		{"missing.cas", "", "ファイルが見つかりません"},
+		{"dir.cas", "", "ファイルが見つかりません"},
+		{"big.cas", "", "ファイルが大きすぎます"},
+		{"link.cas", "", "検索パスの外のファイルは参照できません"},
+		{"../secret.cas", "", "ファイル名が適正ではありません"},
+		{"sub/../../secret.cas", "", "ファイル名が適正ではありません"},
+		{filepath.Join(root, "secret.cas"), "", "ファイル名が適正ではありません"},
+		{`sub\mult.cas`, "", "ファイル名が適正ではありません"},
+		{"", "", "ファイル名が適正ではありません"},
+	}
+	for _, tt := range tests {
+		src, err := p.Source(tt.name)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) || src != tt.src {
+			t.Errorf("%s : %q %v, want %q %s", tt.name, src, err, tt.src, tt.err)
+		}
+	}
+}
+
+func TestSnippets(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "io.cas"), []byte("io"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "readme.txt"), []byte("readme"), 0644)
+	s, err := LoadSnippets(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Snippets{"io": "io"}); !reflect.DeepEqual(s, want) {
+		t.Fatalf("%v, want %v", s, want)
+	}
+	tests := []struct {
+		name string
+		src  string
+		ok   bool
+	}{
+		{"io", "io", true},
+		{"io.cas", "io", true},
+		{"readme", "", false},
+		{"../io.cas", "", false},
+	}
+	for _, tt := range tests {
+		if src, err := s.Source(tt.name); src != tt.src || (err == nil) != tt.ok {
+			t.Errorf("%s : %q %v", tt.name, src, err)
+		}
+	}
+}
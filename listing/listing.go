@@ -0,0 +1,115 @@
+package listing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+)
+
+// WordsPerRow 1行に表示する語数 (超える分は次の行に続ける)
+const WordsPerRow = 2
+
+// Line アセンブルリストの1行
+type Line struct {
+	Line   int      `json:"line,omitempty"` //ソースの行番号 (リテラル・継続行は 0)
+	Addr   *uint16  `json:"addr,omitempty"` //先頭の語の番地 (コードのない行は nil)
+	Words  []uint16 `json:"words,omitempty"`
+	Source string   `json:"source"`
+}
+
+// New LabelToAddress 済みの code とソースからアセンブルリストを作る
+// DS は番地のみ、リテラルはリテラル名をソース欄に表示する
+func New(code []opcode.Opcode, l *lexer.Lexer, symbols *symbol.SymbolTable) []Line {
+	literals := map[uint16]string{}
+	if symbols != nil {
+		for _, sy := range symbols.Symbols() {
+			if strings.HasPrefix(sy.Label, "=") {
+				literals[sy.Address] = sy.Label
+			}
+		}
+	}
+	var lines []Line
+	next := 1 //次に出力するソース行
+	var addr uint16
+	for i := 0; i < len(code); {
+		line := code[i].Token.Line
+		if line == 0 {
+			// リテラル
+			lines = append(lines, row(0, addr, code[i].Words(), "\t"+literals[addr])...)
+			addr += uint16(code[i].Length)
+			i++
+			continue
+		}
+		for ; next < line; next++ {
+			lines = append(lines, Line{Line: next, Source: l.Line(next)})
+		}
+		start := addr
+		var words []uint16
+		for ; i < len(code) && code[i].Token.Line == line; i++ {
+			if code[i].Token.Type != token.DS {
+				words = append(words, code[i].Words()...)
+			}
+			addr += uint16(code[i].Length)
+		}
+		if line < next {
+			// 前の行に続く語 (通常は発生しない)
+			lines = append(lines, row(0, start, words, "")...)
+			continue
+		}
+		lines = append(lines, row(line, start, words, l.Line(line))...)
+		next = line + 1
+	}
+	for ; next <= l.LineCount(); next++ {
+		if next == l.LineCount() && l.Line(next) == "" {
+			break
+		}
+		lines = append(lines, Line{Line: next, Source: l.Line(next)})
+	}
+	return lines
+}
+
+// row words を WordsPerRow 語ずつに分け、2行目以降は継続行にする
+func row(line int, addr uint16, words []uint16, source string) []Line {
+	first := Line{Line: line, Addr: &addr, Source: source}
+	if len(words) <= WordsPerRow {
+		first.Words = words
+		return []Line{first}
+	}
+	first.Words = words[:WordsPerRow]
+	rows := []Line{first}
+	for i := WordsPerRow; i < len(words); i += WordsPerRow {
+		a := addr + uint16(i)
+		end := i + WordsPerRow
+		if end > len(words) {
+			end = len(words)
+		}
+		rows = append(rows, Line{Addr: &a, Words: words[i:end]})
+	}
+	return rows
+}
+
+// Text 行番号・番地・語 (16進)・ソースの表形式
+func Text(lines []Line) string {
+	var b strings.Builder
+	for _, l := range lines {
+		line, addr := "", ""
+		if l.Line > 0 {
+			line = fmt.Sprint(l.Line)
+		}
+		if l.Addr != nil {
+			addr = fmt.Sprintf("%04X", *l.Addr)
+		}
+		var words []string
+		for _, w := range l.Words {
+			words = append(words, fmt.Sprintf("%04X", w))
+		}
+		s := fmt.Sprintf("%4s %4s %-*s %s", line, addr, WordsPerRow*5-1, strings.Join(words, " "), l.Source)
+		b.WriteString(strings.TrimRight(s, " "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package listing
+
+import (
+	"testing"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+)
+
+// list src のアセンブルリスト
+func list(t *testing.T, src string) []Line {
+	t.Helper()
+	p := parser.New(lexer.New(src))
+	code, err := p.Assemble()
+	if err != nil {
+		t.Fatal(err, p.Errors())
+	}
+	return New(code, lexer.New(src), p.SymbolTable())
+}
+
+func TestText(t *testing.T) {
+	src := "; コメント\nMAIN\tSTART\n\tLD\tGR1,=5\n\tST\tGR1,X\n\n\tRET\nX\tDS\t2\nS\tDC\t'ABC'\n\tEND\n"
+	want := `   1                ; コメント
+   2 0000 0000      MAIN	START
+   3 0001 1010 000B 	LD	GR1,=5
+   4 0003 1110 0006 	ST	GR1,X
+   5
+   6 0005 8100      	RET
+   7 0006           X	DS	2
+   8 0008 0041 0042 S	DC	'ABC'
+     000A 0043
+     000B 0005      	=5
+   9 000C 0000      	END
+`
+	if got := Text(list(t, src)); got != want {
+		t.Errorf("\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMacroRows(t *testing.T) {
+	// OUT は PUSH・PUSH・LAD・LAD・SVC・POP・POP の12語
+	lines := list(t, "MAIN\tSTART\n\tOUT\tS,L\n\tRET\nS\tDC\t'A'\nL\tDC\t1\n\tEND\n")
+	var rows []Line
+	for _, l := range lines {
+		if l.Line == 2 || l.Line == 0 && len(rows) > 0 && len(rows) < 6 {
+			rows = append(rows, l)
+		}
+	}
+	if len(rows) != 6 {
+		t.Fatalf("%d行 %+v", len(rows), rows)
+	}
+	for i, r := range rows {
+		if *r.Addr != uint16(1+2*i) || len(r.Words) != 2 {
+			t.Errorf("%d : #%04X %04X", i, *r.Addr, r.Words)
+		}
+	}
+	if rows[0].Source != "\tOUT\tS,L" || rows[1].Source != "" {
+		t.Errorf("%q %q", rows[0].Source, rows[1].Source)
+	}
+}
+
+func TestTrailingLines(t *testing.T) {
+	lines := list(t, "MAIN\tSTART\n\tRET\n\tEND\n; 最後\n")
+	if last := lines[len(lines)-1]; last.Line != 4 || last.Addr != nil || last.Source != "; 最後" {
+		t.Fatalf("%+v", last)
+	}
+}
@@ -0,0 +1,247 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+	"github.com/DJSIer/OnlineGCASL2/include"
+	"github.com/DJSIer/OnlineGCASL2/xref"
+)
+
+// document 開いているソースの解析結果
+type document struct {
+	uri     string
+	lex     *lexer.Lexer
+	code    []opcode.Opcode
+	symbols *symbol.SymbolTable
+	errors  []parser.ParserError
+	warns   []parser.ParserWarning
+	scopes  map[int]int      //line → scope
+	words   map[int][]uint16 //line → 機械語
+	addrs   map[int]uint16   //line → 先頭の番地
+}
+
+// analyze エラーがあっても途中までのプログラムでラベルを解決する
+func analyze(uri, text string) *document {
+	l := lexer.New(text)
+	p := parser.New(l)
+	if u, err := url.Parse(uri); err == nil && u.Scheme == "file" {
+		// INCLUDE はソースと同じディレクトリから探す
+		p.SetIncluder(include.Path{filepath.Dir(filepath.FromSlash(u.Path))}.Source)
+	}
+	code, _ := p.ParseProgram()
+	code, _ = p.LiteralToMemory(code)
+	if resolved, err := p.LabelToAddress(code); err == nil {
+		code = resolved
+	}
+	d := &document{
+		uri:     uri,
+		lex:     l,
+		code:    code,
+		symbols: p.SymbolTable(),
+		errors:  p.Errors(),
+		warns:   p.Warnings(),
+		scopes:  map[int]int{},
+		words:   map[int][]uint16{},
+		addrs:   map[int]uint16{},
+	}
+	var addr uint16
+	for _, op := range code {
+		if line := op.Token.Line; line > 0 {
+			d.scopes[line] = op.Scope
+			if _, ok := d.addrs[line]; !ok {
+				d.addrs[line] = addr
+			}
+			if op.Token.Type != token.DS {
+				d.words[line] = append(d.words[line], op.Words()...)
+			}
+		}
+		addr += uint16(op.Length)
+	}
+	if len(d.errors) == 0 {
+		d.warns = append(d.warns, xref.Warnings(xref.New(code, d.symbols))...)
+	}
+	return d
+}
+
+// line 1 始まりの行番号のソース
+func (d *document) line(n int) string {
+	return d.lex.Line(n)
+}
+
+// scope n 行目のプログラム (コードのない行は直前の行のもの)
+func (d *document) scope(n int) int {
+	for ; n > 0; n-- {
+		if s, ok := d.scopes[n]; ok {
+			return s
+		}
+	}
+	return d.symbols.Scope()
+}
+
+// diagnostics ParserError・ParserWarning の範囲 (範囲のないものは行全体) とコード
+// メッセージは lang のもの
+func (d *document) diagnostics(lang string) []Diagnostic {
+	diags := []Diagnostic{}
+	for _, e := range parser.LocalizeErrors(d.errors, lang) {
+		diags = append(diags, Diagnostic{Range: d.spanRange(e.Line, e.Column, e.EndColumn), Severity: severityError, Code: e.Code, Source: "gcasl", Message: includeMessage(e.Include, e.Message)})
+	}
+	for _, w := range parser.LocalizeWarnings(d.warns, lang) {
+		diags = append(diags, Diagnostic{Range: d.spanRange(w.Line, w.Column, w.EndColumn), Severity: severityWarning, Code: w.Code, Source: "gcasl", Message: includeMessage(w.Include, w.Message)})
+	}
+	return diags
+}
+
+// includeMessage INCLUDE したファイルの中のエラーは file:line を内側から前に付ける (範囲は INCLUDE の行)
+func includeMessage(stack []string, msg string) string {
+	msg = strings.TrimSpace(msg)
+	for i := len(stack) - 1; i >= 0; i-- {
+		msg = stack[i] + ": " + msg
+	}
+	return msg
+}
+
+func (d *document) lineRange(n int) Range {
+	if n < 1 {
+		n = 1
+	}
+	s := d.line(n)
+	return Range{Start: Position{Line: n - 1}, End: Position{Line: n - 1, Character: utf16Len(s)}}
+}
+
+// spanRange n 行目の column〜end (行頭を 1 とするバイト位置、end は範囲の次)
+func (d *document) spanRange(n, column, end int) Range {
+	s := d.line(n)
+	if n < 1 || column < 1 || column > len(s)+1 {
+		return d.lineRange(n)
+	}
+	if end < column || end > len(s)+1 {
+		end = len(s) + 1
+	}
+	return Range{Start: Position{Line: n - 1, Character: utf16Len(s[:column-1])}, End: Position{Line: n - 1, Character: utf16Len(s[:end-1])}}
+}
+
+// wordAt 位置にある語 (ラベル・命令・レジスタ) と 1 始まりの行番号
+func (d *document) wordAt(pos Position) (string, int, Range) {
+	n := pos.Line + 1
+	s := d.line(n)
+	i := byteOffset(s, pos.Character)
+	start, end := i, i
+	for start > 0 && isWord(s[start-1]) {
+		start--
+	}
+	for end < len(s) && isWord(s[end]) {
+		end++
+	}
+	if c := strings.IndexByte(s, ';'); c >= 0 && c < start {
+		// コメント中
+		return "", n, Range{}
+	}
+	r := Range{Start: Position{Line: pos.Line, Character: utf16Len(s[:start])}, End: Position{Line: pos.Line, Character: utf16Len(s[:end])}}
+	return s[start:end], n, r
+}
+
+// wordRange n 行目の最初の word (ラベル欄を除くなら skipLabel)
+func (d *document) wordRange(n int, word string, skipLabel bool) Range {
+	s := d.line(n)
+	from := 0
+	if skipLabel {
+		for from < len(s) && isWord(s[from]) {
+			from++
+		}
+	}
+	for i := from; i+len(word) <= len(s); i++ {
+		if s[i:i+len(word)] == word && (i == 0 || !isWord(s[i-1])) && (i+len(word) == len(s) || !isWord(s[i+len(word)])) {
+			return Range{Start: Position{Line: n - 1, Character: utf16Len(s[:i])}, End: Position{Line: n - 1, Character: utf16Len(s[:i+len(word)])}}
+		}
+	}
+	return d.lineRange(n)
+}
+
+// resolve n 行目から参照した label
+func (d *document) resolve(label string, n int) (symbol.Symbol, bool) {
+	if token.LookupInst(label) != token.LABEL {
+		return symbol.Symbol{}, false
+	}
+	return d.symbols.ResolveIn(d.scope(n), label)
+}
+
+// references sy を AddrLabel (式を含む) で参照している行
+func (d *document) references(sy symbol.Symbol) []int {
+	var lines []int
+	for _, op := range d.code {
+		if op.AddrLabel == "" || op.Token.Line == 0 {
+			continue
+		}
+		e, err := parser.ParseExpr(op.AddrLabel)
+		if err != nil {
+			continue
+		}
+		for _, label := range e.Labels() {
+			if r, ok := d.symbols.ResolveIn(op.Scope, label); ok && r.Index == sy.Index {
+				if len(lines) == 0 || lines[len(lines)-1] != op.Token.Line {
+					lines = append(lines, op.Token.Line)
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// symbolKind ラベルの種類 (EQU・DC は定数、DS は領域、それ以外は命令)
+func (d *document) symbolKind(sy symbol.Symbol) int {
+	if sy.Kind == symbol.KindConstant {
+		return symbolConstant
+	}
+	for _, op := range d.code {
+		if op.Token.Line != sy.Line {
+			continue
+		}
+		switch op.Token.Type {
+		case token.DC:
+			return symbolConstant
+		case token.DS:
+			return symbolVariable
+		}
+		return symbolFunction
+	}
+	return symbolVariable
+}
+
+func isWord(ch byte) bool {
+	return 'A' <= ch && ch <= 'Z' || 'a' <= ch && ch <= 'z' || '0' <= ch && ch <= '9'
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeLen(r)
+	}
+	return n
+}
+
+// runeLen UTF-16 での長さ
+func runeLen(r rune) int {
+	if r > 0xFFFF {
+		return 2
+	}
+	return 1
+}
+
+// byteOffset UTF-16 の文字位置を s のバイト位置にする
+func byteOffset(s string, character int) int {
+	n := 0
+	for i, r := range s {
+		if n >= character {
+			return i
+		}
+		n += runeLen(r)
+	}
+	return len(s)
+}
@@ -0,0 +1,82 @@
+package lsp
+
+import "github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+
+// instruction hover・completion に表示する命令の書式と動作
+type instruction struct {
+	Mnemonic string
+	Syntax   string
+	Summary  string
+}
+
+// instructions CASL2 の命令・擬似命令・マクロ命令 (機械語命令は init で opcode.Instructions から加える)
+var instructions = []instruction{
+	{"START", "ラベル START [実行開始番地]", "プログラムの先頭。ラベルは他のプログラムから参照できる"},
+	{"END", "END", "プログラムの終わり。リテラルはここに配置される"},
+	{"DS", "[ラベル] DS 語数", "領域を確保する (語数は定数でもよい)"},
+	{"DC", "[ラベル] DC 定数[,定数]...", "定数 (10進・#16進・'文字列'・ラベル) を定義する"},
+	{"EQU", "名前 EQU 値", "名前を定数 (10進・#16進・定義済みの定数) として定義する。番地ではないので再配置されない"},
+	{"IF", "IF 値", "値が 0 以外なら ELSE または ENDIF までをアセンブルする"},
+	{"ELSE", "ELSE", "IF の条件が成り立たないときにアセンブルする部分の始まり"},
+	{"ENDIF", "ENDIF", "IF の終わり"},
+	{"INCLUDE", "INCLUDE 'ファイル名'", "ファイルのソースをこの行に取り込む"},
+	{"MACRO", "名前 MACRO [&引数[,&引数]...]", "MEND までをマクロとして定義する。本体で定義したラベルは展開ごとに別名になる"},
+	{"MEND", "MEND", "マクロ定義の終わり"},
+	{"IN", "[ラベル] IN 入力領域,入力文字長", "入力装置から1レコード読み込む"},
+	{"OUT", "[ラベル] OUT 出力領域,出力文字長", "出力装置へ1レコード書き出す"},
+	{"RPUSH", "[ラベル] RPUSH", "GR1〜GR7 をスタックに退避する"},
+	{"RPOP", "[ラベル] RPOP", "GR7〜GR1 をスタックから復元する"},
+}
+
+// summaries 機械語命令の動作 (書式は opcode.Instructions から作る)
+var summaries = map[string]string{
+	"NOP":  "何もしない",
+	"LD":   "r ← (実効アドレス)  FR: OF=0, SF, ZF",
+	"ST":   "実効アドレス ← (r)",
+	"LAD":  "r ← 実効アドレス",
+	"ADDA": "r ← (r) + (実効アドレス)  算術加算  FR: OF, SF, ZF",
+	"SUBA": "r ← (r) - (実効アドレス)  算術減算  FR: OF, SF, ZF",
+	"ADDL": "r ← (r) + (実効アドレス)  論理加算  FR: OF, SF, ZF",
+	"SUBL": "r ← (r) - (実効アドレス)  論理減算  FR: OF, SF, ZF",
+	"AND":  "r ← (r) AND (実効アドレス)  FR: OF=0, SF, ZF",
+	"OR":   "r ← (r) OR (実効アドレス)  FR: OF=0, SF, ZF",
+	"XOR":  "r ← (r) XOR (実効アドレス)  FR: OF=0, SF, ZF",
+	"CPA":  "(r) と (実効アドレス) を算術比較する  FR: OF=0, SF, ZF",
+	"CPL":  "(r) と (実効アドレス) を論理比較する  FR: OF=0, SF, ZF",
+	"SLA":  "(r) を実効アドレスのビット数だけ算術左シフトする (符号は保存)",
+	"SRA":  "(r) を実効アドレスのビット数だけ算術右シフトする (空いたビットは符号)",
+	"SLL":  "(r) を実効アドレスのビット数だけ論理左シフトする",
+	"SRL":  "(r) を実効アドレスのビット数だけ論理右シフトする",
+	"JPL":  "SF=0 かつ ZF=0 なら実効アドレスに分岐する",
+	"JMI":  "SF=1 なら実効アドレスに分岐する",
+	"JNZ":  "ZF=0 なら実効アドレスに分岐する",
+	"JZE":  "ZF=1 なら実効アドレスに分岐する",
+	"JOV":  "OF=1 なら実効アドレスに分岐する",
+	"JUMP": "実効アドレスに分岐する",
+	"PUSH": "SP ← (SP) - 1, (SP) ← 実効アドレス",
+	"POP":  "r ← ((SP)), SP ← (SP) + 1",
+	"CALL": "SP ← (SP) - 1, (SP) ← (PR), PR ← 実効アドレス",
+	"RET":  "PR ← ((SP)), SP ← (SP) + 1",
+	"SVC":  "実効アドレスを番号とするスーパバイザコール",
+}
+
+var instructionByMnemonic = map[string]instruction{}
+
+func init() {
+	index := map[string]int{}
+	for _, op := range opcode.Instructions {
+		if i, ok := index[op.Mnemonic]; ok {
+			// LD r,adr[,x] と LD r1,r2 のように同じ命令名の形式はまとめる
+			instructions[i].Syntax += " / " + op.Syntax()
+			continue
+		}
+		index[op.Mnemonic] = len(instructions)
+		instructions = append(instructions, instruction{op.Mnemonic, op.Syntax(), summaries[op.Mnemonic]})
+	}
+	for _, inst := range instructions {
+		instructionByMnemonic[inst.Mnemonic] = inst
+	}
+}
+
+// registers 汎用レジスタ
+var registers = []string{"GR0", "GR1", "GR2", "GR3", "GR4", "GR5", "GR6", "GR7"}
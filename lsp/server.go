@@ -0,0 +1,324 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+)
+
+// Server CASL2 Language Server (JSON-RPC over stdio)
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	mu   sync.Mutex //out への書き込み
+	docs map[string]*document
+	lang string //initialize の locale から選んだ診断メッセージの言語
+
+	shutdown bool
+}
+
+// NewServer r から要求を読み、w に応答を書く
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{in: bufio.NewReader(r), out: w, docs: map[string]*document{}}
+}
+
+// Serve exit 通知または入力の終わりまで要求を処理する (shutdown なしの exit はエラー)
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("shutdown の前に exit を受け取りました。")
+			}
+			return nil
+		}
+		s.handle(msg)
+	}
+}
+
+// read Content-Length ヘッダ付きのメッセージを1つ読む
+func (s *Server) read() (*message, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("Content-Length が不正です。 : %v", err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	msg := &message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		s.write(&message{JSONRPC: "2.0", Error: &responseError{Code: codeParseError, Message: err.Error()}})
+		return nil, nil
+	}
+	return msg, nil
+}
+
+func (s *Server) write(msg *message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	b, _ := json.Marshal(params)
+	s.write(&message{JSONRPC: "2.0", Method: method, Params: b})
+}
+
+func (s *Server) handle(msg *message) {
+	result, err := s.dispatch(msg)
+	if msg.ID == nil {
+		// notification
+		return
+	}
+	res := &message{JSONRPC: "2.0", ID: msg.ID}
+	if err != nil {
+		res.Error = err
+	} else if result == nil {
+		res.Result = json.RawMessage("null")
+	} else {
+		res.Result = result
+	}
+	s.write(res)
+}
+
+func (s *Server) dispatch(msg *message) (interface{}, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		var p initializeParams
+		if len(msg.Params) > 0 {
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				return nil, invalidParams(err)
+			}
+		}
+		s.lang = parser.MatchLanguage(p.Locale)
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":       1, //full
+				"definitionProvider":     true,
+				"referencesProvider":     true,
+				"hoverProvider":          true,
+				"documentSymbolProvider": true,
+				"completionProvider":     map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "gcasl-lsp"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.update(p.TextDocument.URI, p.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			s.update(p.TextDocument.URI, p.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.docs, p.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		return nil, nil
+	case "textDocument/definition", "textDocument/references", "textDocument/hover", "textDocument/completion":
+		var p positionParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		d, ok := s.docs[p.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+		switch msg.Method {
+		case "textDocument/definition":
+			return definition(d, p.Position), nil
+		case "textDocument/references":
+			return references(d, p.Position, p.Context.IncludeDeclaration), nil
+		case "textDocument/hover":
+			return hover(d, p.Position), nil
+		default:
+			return completion(d, p.Position), nil
+		}
+	case "textDocument/documentSymbol":
+		var p documentSymbolParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		d, ok := s.docs[p.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+		return documentSymbols(d), nil
+	}
+	if strings.HasPrefix(msg.Method, "$/") {
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("%q : 未対応のメソッドです。", msg.Method)}
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+// update ソースを解析し直し、diagnostics を送る
+func (s *Server) update(uri, text string) {
+	d := analyze(uri, text)
+	s.docs[uri] = d
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: d.diagnostics(s.lang)})
+}
+
+// definition ラベルの定義位置
+func definition(d *document, pos Position) interface{} {
+	word, n, _ := d.wordAt(pos)
+	sy, ok := d.resolve(word, n)
+	if !ok || sy.Line == 0 {
+		return nil
+	}
+	return Location{URI: d.uri, Range: d.wordRange(sy.Line, sy.Label, false)}
+}
+
+// references ラベルを参照している位置
+func references(d *document, pos Position, declaration bool) []Location {
+	locations := []Location{}
+	word, n, _ := d.wordAt(pos)
+	sy, ok := d.resolve(word, n)
+	if !ok {
+		return locations
+	}
+	if declaration && sy.Line > 0 {
+		locations = append(locations, Location{URI: d.uri, Range: d.wordRange(sy.Line, sy.Label, false)})
+	}
+	for _, line := range d.references(sy) {
+		locations = append(locations, Location{URI: d.uri, Range: d.wordRange(line, sy.Label, true)})
+	}
+	return locations
+}
+
+// hover 命令は書式・動作と機械語、ラベルは番地と定義行
+func hover(d *document, pos Position) interface{} {
+	word, n, r := d.wordAt(pos)
+	if word == "" {
+		return nil
+	}
+	var b strings.Builder
+	if inst, ok := instructionByMnemonic[word]; ok {
+		fmt.Fprintf(&b, "```\n%s\n```\n%s", inst.Syntax, inst.Summary)
+		if words, ok := d.words[n]; ok && len(words) > 0 {
+			hex := make([]string, len(words))
+			for i, w := range words {
+				hex[i] = fmt.Sprintf("#%04X", w)
+			}
+			fmt.Fprintf(&b, "\n\n#%04X : %s", d.addrs[n], strings.Join(hex, " "))
+		}
+	} else if sy, ok := d.resolve(word, n); ok {
+		if sy.Kind == symbol.KindConstant {
+			fmt.Fprintf(&b, "**%s** 定数 %d (#%04X)", sy.Label, int16(sy.Address), sy.Address)
+		} else {
+			fmt.Fprintf(&b, "**%s** #%04X (%d)", sy.Label, sy.Address, sy.Address)
+		}
+		if sy.Line > 0 {
+			fmt.Fprintf(&b, "\n\n%d行目 : `%s`", sy.Line, strings.TrimSpace(d.line(sy.Line)))
+		}
+	} else {
+		return nil
+	}
+	return Hover{Contents: markupContent{Kind: "markdown", Value: b.String()}, Range: &r}
+}
+
+// completion 命令・レジスタ・その行から参照できるラベル
+func completion(d *document, pos Position) []CompletionItem {
+	var items []CompletionItem
+	for _, inst := range instructions {
+		items = append(items, CompletionItem{Label: inst.Mnemonic, Kind: completionKeyword, Detail: inst.Syntax})
+	}
+	for _, r := range registers {
+		items = append(items, CompletionItem{Label: r, Kind: completionVariable})
+	}
+	scope := d.scope(pos.Line + 1)
+	seen := map[string]bool{}
+	for _, sy := range d.symbols.Symbols() {
+		if !isSourceLabel(sy.Label) || seen[sy.Label] {
+			continue
+		}
+		if r, ok := d.symbols.ResolveIn(scope, sy.Label); !ok || r.Index != sy.Index {
+			continue
+		}
+		seen[sy.Label] = true
+		kind := completionVariable
+		if sy.Scope != scope {
+			kind = completionModule
+		}
+		items = append(items, CompletionItem{Label: sy.Label, Kind: kind, Detail: fmt.Sprintf("#%04X", sy.Address)})
+	}
+	return items
+}
+
+// documentSymbols START ラベル (プログラム) とその中のラベル
+func documentSymbols(d *document) []SymbolInformation {
+	symbols := []SymbolInformation{}
+	programs := map[int]string{}
+	for _, sy := range d.symbols.Entries() {
+		programs[sy.Scope] = sy.Label
+	}
+	for _, sy := range d.symbols.Symbols() {
+		if !isSourceLabel(sy.Label) || sy.Line == 0 {
+			continue
+		}
+		info := SymbolInformation{Name: sy.Label, Kind: symbolVariable, Location: Location{URI: d.uri, Range: d.wordRange(sy.Line, sy.Label, false)}}
+		if programs[sy.Scope] == sy.Label {
+			info.Kind = symbolModule
+		} else {
+			info.ContainerName = programs[sy.Scope]
+			info.Kind = d.symbolKind(sy)
+		}
+		symbols = append(symbols, info)
+	}
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return symbols[i].Location.Range.Start.Line < symbols[j].Location.Range.Start.Line
+	})
+	return symbols
+}
+
+// isSourceLabel リテラル (=...) とマクロのローカルラベル (NAME.n) 以外
+func isSourceLabel(label string) bool {
+	return !strings.HasPrefix(label, "=") && !strings.Contains(label, ".")
+}
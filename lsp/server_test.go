@@ -0,0 +1,265 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const uri = "file:///tmp/test.cas"
+
+// 2つのプログラムに同じラベル X がある
+const src = `N	EQU	3
+MAIN	START
+	LD	GR1,X	; X
+	CALL	SUB
+	RET
+X	DC	5
+	END
+SUB	START
+	LD	GR2,X
+	LAD	GR3,N
+	RET
+X	DS	1
+	END
+`
+
+func rng(line, start, end int) Range {
+	return Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}
+}
+
+func TestDefinition(t *testing.T) {
+	d := analyze(uri, src)
+	tests := []struct {
+		name string
+		pos  Position
+		want interface{}
+	}{
+		{"main", Position{Line: 2, Character: 8}, Location{URI: uri, Range: rng(5, 0, 1)}},
+		{"sub", Position{Line: 8, Character: 8}, Location{URI: uri, Range: rng(11, 0, 1)}},
+		{"call", Position{Line: 3, Character: 7}, Location{URI: uri, Range: rng(7, 0, 3)}},
+		{"constant", Position{Line: 9, Character: 9}, Location{URI: uri, Range: rng(0, 0, 1)}},
+		{"register", Position{Line: 2, Character: 5}, nil},
+		{"comment", Position{Line: 2, Character: 11}, nil},
+	}
+	for _, tt := range tests {
+		if got := definition(d, tt.pos); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	d := analyze(uri, src)
+	tests := []struct {
+		name        string
+		pos         Position
+		declaration bool
+		want        []Location
+	}{
+		{"main", Position{Line: 5, Character: 0}, false, []Location{{URI: uri, Range: rng(2, 8, 9)}}},
+		{"declaration", Position{Line: 11, Character: 0}, true, []Location{{URI: uri, Range: rng(11, 0, 1)}, {URI: uri, Range: rng(8, 8, 9)}}},
+		{"program", Position{Line: 7, Character: 1}, false, []Location{{URI: uri, Range: rng(3, 6, 9)}}},
+		{"none", Position{Line: 4, Character: 2}, true, []Location{}},
+	}
+	for _, tt := range tests {
+		if got := references(d, tt.pos, tt.declaration); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s : %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	d := analyze(uri, src)
+	tests := []struct {
+		name string
+		pos  Position
+		want string
+	}{
+		{"instruction", Position{Line: 2, Character: 2}, "#0001 : #1010 #0006"},
+		{"label", Position{Line: 2, Character: 8}, "**X** #0006 (6)\n\n6行目 : `X\tDC\t5`"},
+		{"constant", Position{Line: 9, Character: 9}, "**N** 定数 3 (#0003)\n\n1行目 : `N\tEQU\t3`"},
+		{"empty", Position{Line: 2, Character: 0}, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if h, ok := hover(d, tt.pos).(Hover); ok {
+			got = h.Contents.Value
+		}
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("%s : %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	d := analyze(uri, src)
+	labels := func(line int) map[string]CompletionItem {
+		items := map[string]CompletionItem{}
+		for _, item := range completion(d, Position{Line: line}) {
+			items[item.Label] = item
+		}
+		return items
+	}
+	main, sub := labels(3), labels(9)
+	if main["LD"].Kind != completionKeyword || main["GR7"].Kind != completionVariable {
+		t.Errorf("LD %+v GR7 %+v", main["LD"], main["GR7"])
+	}
+	// X はプログラムごとのラベル、SUB は他のプログラムから参照できる
+	if x := main["X"]; x.Kind != completionVariable || x.Detail != "#0006" {
+		t.Errorf("MAIN の X %+v", x)
+	}
+	if x := sub["X"]; x.Kind != completionVariable || x.Detail != "#000E" {
+		t.Errorf("SUB の X %+v", x)
+	}
+	if s := main["SUB"]; s.Kind != completionModule {
+		t.Errorf("SUB %+v", s)
+	}
+	for _, label := range []string{"=5"} {
+		if _, ok := main[label]; ok {
+			t.Errorf("%s が補完されます", label)
+		}
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	got := documentSymbols(analyze(uri, src))
+	want := []SymbolInformation{
+		{Name: "N", Kind: symbolConstant, Location: Location{URI: uri, Range: rng(0, 0, 1)}},
+		{Name: "MAIN", Kind: symbolModule, Location: Location{URI: uri, Range: rng(1, 0, 4)}},
+		{Name: "X", Kind: symbolConstant, Location: Location{URI: uri, Range: rng(5, 0, 1)}, ContainerName: "MAIN"},
+		{Name: "SUB", Kind: symbolModule, Location: Location{URI: uri, Range: rng(7, 0, 3)}},
+		{Name: "X", Kind: symbolVariable, Location: Location{URI: uri, Range: rng(11, 0, 1)}, ContainerName: "SUB"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%+v, want %+v", got, want)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	d := analyze(uri, "MAIN\tSTART\n\tLD\tGR9,X\n\tRET\nX\tDS\t1\nY\tDS\t1\n\tEND\n")
+	tests := []struct {
+		lang string
+		want []Diagnostic
+	}{
+		{"ja", []Diagnostic{{Range: rng(1, 4, 7), Severity: severityError, Code: "E0103", Source: "gcasl", Message: "LD \"GR9\" \n\"GR9\"はレジスタではありません。"}}},
+		{"en", []Diagnostic{{Range: rng(1, 4, 7), Severity: severityError, Code: "E0103", Source: "gcasl", Message: "LD \"GR9\" \n\"GR9\" is not a register."}}},
+	}
+	for _, tt := range tests {
+		if got := d.diagnostics(tt.lang); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s : %+v, want %+v", tt.lang, got, tt.want)
+		}
+	}
+	// エラーがなければ未使用ラベルの警告
+	d = analyze(uri, "MAIN\tSTART\n\tLD\tGR1,X\n\tRET\nX\tDS\t1\nY\tDS\t1\n\tEND\n")
+	if got := d.diagnostics("ja"); len(got) != 1 || got[0].Severity != severityWarning || got[0].Range != rng(4, 0, 1) {
+		t.Errorf("%+v", got)
+	}
+}
+
+func TestUTF16(t *testing.T) {
+	d := analyze(uri, "MAIN\tSTART\n\tLD\tGR1,X\t; 😀 X\n\tRET\nX\tDS\t1\n\tEND\n")
+	if word, _, _ := d.wordAt(Position{Line: 1, Character: 15}); word != "" {
+		t.Errorf("コメント中の %q", word)
+	}
+	// 😀 は UTF-16 で2文字
+	if r := d.spanRange(2, 18, 19); r != rng(1, 15, 16) {
+		t.Errorf("%+v", r)
+	}
+	if got := byteOffset("😀X", 2); got != 4 {
+		t.Errorf("byteOffset %d, want 4", got)
+	}
+	if got := utf16Len("あ😀"); got != 3 {
+		t.Errorf("utf16Len %d, want 3", got)
+	}
+}
+
+// request Content-Length 付きのメッセージ
+func request(id int, method string, params interface{}) string {
+	msg := map[string]interface{}{"jsonrpc": "2.0", "method": method}
+	if id > 0 {
+		msg["id"] = id
+	}
+	if params != nil {
+		msg["params"] = params
+	}
+	b, _ := json.Marshal(msg)
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(b), b)
+}
+
+// responses 出力のメッセージ
+func responses(t *testing.T, out *bytes.Buffer) []message {
+	t.Helper()
+	var msgs []message
+	r := bufio.NewReader(out)
+	for {
+		var length int
+		if _, err := fmt.Fscanf(r, "Content-Length: %d\r\n\r\n", &length); err != nil {
+			return msgs
+		}
+		body := make([]byte, length)
+		if _, err := r.Read(body); err != nil {
+			t.Fatal(err)
+		}
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestServe(t *testing.T) {
+	doc := map[string]interface{}{"uri": uri}
+	in := strings.Join([]string{
+		request(1, "initialize", map[string]interface{}{"locale": "en-US"}),
+		request(0, "initialized", map[string]interface{}{}),
+		request(0, "textDocument/didOpen", map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri, "languageId": "casl2", "version": 1, "text": "MAIN\tSTART\n\tLD\tGR9,X\n\tEND\n"}}),
+		request(0, "textDocument/didChange", map[string]interface{}{"textDocument": doc, "contentChanges": []interface{}{map[string]string{"text": src}}}),
+		request(2, "textDocument/definition", map[string]interface{}{"textDocument": doc, "position": Position{Line: 2, Character: 8}}),
+		request(3, "textDocument/unknown", map[string]interface{}{}),
+		request(0, "$/cancelRequest", map[string]interface{}{"id": 2}),
+		request(4, "shutdown", nil),
+		request(0, "exit", nil),
+	}, "")
+	var out bytes.Buffer
+	if err := NewServer(strings.NewReader(in), &out).Serve(); err != nil {
+		t.Fatal(err)
+	}
+	msgs := responses(t, &out)
+	if len(msgs) != 6 {
+		t.Fatalf("%d messages, want 6", len(msgs))
+	}
+	raw := func(v interface{}) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"initialize", raw(msgs[0].Result), `"hoverProvider":true`},
+		{"didOpen", string(msgs[1].Params), `"GR9\" is not a register.`},
+		{"didChange", string(msgs[2].Params), `"diagnostics":[]`},
+		{"definition", raw(msgs[3].Result), `"start":{"character":0,"line":5}`},
+		{"unknown", msgs[4].Error.Message, "未対応のメソッドです"},
+		{"shutdown", string(*msgs[5].ID), "4"},
+	}
+	for _, tt := range tests {
+		if !strings.Contains(tt.got, tt.want) {
+			t.Errorf("%s : %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestServeExit(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewServer(strings.NewReader(request(0, "exit", nil)), &out).Serve(); err == nil {
+		t.Fatal("shutdown なしの exit がエラーになりません")
+	}
+}
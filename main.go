@@ -3,18 +3,36 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
-	"github.com/DJSIer/GCASL2/lexer"
-	"github.com/DJSIer/GCASL2/parser"
+	"github.com/DJSIer/OnlineGCASL2/comet2"
+	"github.com/DJSIer/OnlineGCASL2/debugger"
+	"github.com/DJSIer/OnlineGCASL2/disasm"
+	"github.com/DJSIer/OnlineGCASL2/format"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+	"github.com/DJSIer/OnlineGCASL2/include"
+	"github.com/DJSIer/OnlineGCASL2/listing"
+	"github.com/DJSIer/OnlineGCASL2/websocket"
+	"github.com/DJSIer/OnlineGCASL2/xref"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 )
 
 const version = "0.1.21"
 
+// runStepLimit /GCASL/run で実行する命令数の上限
+const runStepLimit = 1000000
+
+// library INCLUDE で取り込める共通ライブラリ ($GCASL_LIBRARY のディレクトリの *.cas)
+var library = include.Snippets{}
+
 // ShareCode URL id binding
 type ShareCode struct {
 	ID string `uri:"id" binding:"required"`
@@ -26,6 +44,12 @@ func main() {
 	if port == "" {
 		log.Fatal("$PORT must be set")
 	}
+	if dir := os.Getenv("GCASL_LIBRARY"); dir != "" {
+		var err error
+		if library, err = include.LoadSnippets(dir); err != nil {
+			log.Fatal(err)
+		}
+	}
 	router := gin.Default()
 	router.LoadHTMLGlob("WOCASL2/*.html")
 
@@ -64,24 +88,32 @@ func main() {
 
 	router.POST("/GCASL", func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
+		lang := language(c)
 		postCode := c.PostForm("code")
 		lex := lexer.New(postCode)
 		p := parser.New(lex)
+		p.SetIncluder(library.Source)
+		// 誤りは ParseProgram のエラーになる
+		p.DefineConstants(c.PostForm("define"))
 		code, err := p.ParseProgram()
 		if err != nil {
-			var buf bytes.Buffer
-			b, _ := json.Marshal(p.Errors())
+			var buf, codebuf bytes.Buffer
+			b, _ := json.Marshal(parser.LocalizeErrors(p.Errors(), lang))
 			buf.Write(b)
+			//エラー行を除いた途中までの機械語
+			bb, _ := json.Marshal(code)
+			codebuf.Write(bb)
 			c.JSON(200, gin.H{
 				"result": "NG",
 				"error":  buf.String(),
+				"code":   codebuf.String(),
 			})
 		} else {
 			code, err = p.LiteralToMemory(code)
 			code, err = p.LabelToAddress(code)
 			if err != nil {
 				var buf bytes.Buffer
-				b, _ := json.Marshal(p.Errors())
+				b, _ := json.Marshal(parser.LocalizeErrors(p.Errors(), lang))
 				buf.Write(b)
 				c.JSON(200, gin.H{
 					"result": "NG",
@@ -91,15 +123,197 @@ func main() {
 				var buf, warbuf bytes.Buffer
 				b, _ := json.Marshal(code)
 				buf.Write(b)
-				bb, _ := json.Marshal(p.Warnings())
+				warnings := p.Warnings()
+				var entries []xref.Entry
+				if c.PostForm("xref") != "" {
+					entries = xref.New(code, p.SymbolTable())
+					warnings = append(warnings, xref.Warnings(entries)...)
+				}
+				bb, _ := json.Marshal(parser.LocalizeWarnings(warnings, lang))
 				warbuf.Write(bb)
-				c.JSON(200, gin.H{
+				entry, _ := p.Entry()
+				res := gin.H{
 					"result":  "OK",
 					"code":    buf.String(),
 					"warning": warbuf,
+					"entry":   entry,
+				}
+				if c.PostForm("listing") != "" {
+					res["listing"] = listing.Text(listing.New(code, lex, p.SymbolTable()))
+				}
+				if entries != nil {
+					res["xref"] = entries
+				}
+				c.JSON(200, res)
+			}
+		}
+	})
+	//debug : curl -F "code=value1" -F "input=value2" [-F "trace=jsonl"] [-F "define=DEBUG=1"] localhost:8080/GCASL/run
+	router.POST("/GCASL/run", run)
+	//debug : curl -F "words=1210 0005 8100" [-F "addr=#0000"] localhost:8080/GCASL/disasm
+	router.POST("/GCASL/disasm", func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+		var words []uint16
+		for _, f := range strings.FieldsFunc(c.PostForm("words"), func(r rune) bool {
+			return r == ' ' || r == ',' || r == '\n' || r == '\r' || r == '\t'
+		}) {
+			w, err := strconv.ParseUint(strings.TrimPrefix(f, "#"), 16, 16)
+			if err != nil {
+				c.JSON(200, gin.H{
+					"result": "NG",
+					"error":  fmt.Sprintf("%q : 16進数4桁で指定してください。", f),
 				})
+				return
+			}
+			words = append(words, uint16(w))
+		}
+		var start uint64
+		if addr := c.PostForm("addr"); addr != "" {
+			var err error
+			if start, err = strconv.ParseUint(strings.TrimPrefix(addr, "#"), 16, 16); err != nil {
+				c.JSON(200, gin.H{
+					"result": "NG",
+					"error":  fmt.Sprintf("%q : 16進数4桁で指定してください。", addr),
+				})
+				return
+			}
+		}
+		lines := disasm.New(nil).Disassemble(words, uint16(start))
+		c.JSON(200, gin.H{
+			"result": "OK",
+			"lines":  lines,
+			"source": disasm.Source(lines),
+		})
+	})
+	//debug : curl -F "code=value1" localhost:8080/GCASL/format
+	router.POST("/GCASL/format", func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.JSON(200, gin.H{
+			"result": "OK",
+			"source": format.Source(c.PostForm("code")),
+		})
+	})
+	//debug : curl [-H "Accept-Language: en"] "localhost:8080/GCASL/codes[?lang=en]"
+	router.GET("/GCASL/codes", func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+		lang := language(c)
+		c.JSON(200, gin.H{
+			"result": "OK",
+			"lang":   lang,
+			"codes":  parser.Catalog(lang),
+		})
+	})
+	// WebSocket : {"command":"load","code":"..."} → {"command":"step"} ...
+	router.GET("/GCASL/debug", func(c *gin.Context) {
+		conn, err := websocket.Upgrade(c.Writer, c.Request)
+		if err != nil {
+			c.JSON(400, gin.H{"msg": err.Error()})
+			return
+		}
+		defer conn.Close()
+		s := debugger.NewSession()
+		s.Includer = library.Source
+		s.Lang = language(c)
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req debugger.Request
+			var res *debugger.Response
+			if err := json.Unmarshal(msg, &req); err != nil {
+				res = &debugger.Response{Result: "NG", Error: err.Error()}
+			} else {
+				res = s.Handle(req)
+			}
+			b, _ := json.Marshal(res)
+			if err := conn.WriteMessage(b); err != nil {
+				return
 			}
 		}
 	})
 	router.Run(":" + port)
 }
+
+// run POST /GCASL/run code をアセンブルし、input を標準入力として実行する
+func run(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*")
+	code, p, err := assemble(c.PostForm("code"), c.PostForm("define"))
+	if err != nil {
+		var buf bytes.Buffer
+		b, _ := json.Marshal(parser.LocalizeErrors(p.Errors(), language(c)))
+		buf.Write(b)
+		c.JSON(200, gin.H{
+			"result": "NG",
+			"error":  buf.String(),
+		})
+		return
+	}
+	var out bytes.Buffer
+	m := comet2.New()
+	m.Stdin = strings.NewReader(c.PostForm("input"))
+	m.Stdout = &out
+	trace := c.PostForm("trace")
+	switch trace {
+	case "":
+	case "jsonl":
+		c.Header("Content-Type", "application/x-ndjson")
+		m.Tracer = comet2.NewJSONLTracer(c.Writer)
+	case "csv":
+		c.Header("Content-Type", "text/csv")
+		m.Tracer = comet2.NewCSVTracer(c.Writer)
+	default:
+		c.JSON(200, gin.H{
+			"result": "NG",
+			"error":  fmt.Sprintf("%q : trace は jsonl・csv のいずれかです。", trace),
+		})
+		return
+	}
+	if err = m.Load(code); err == nil {
+		err = m.Run(runStepLimit)
+	}
+	result := gin.H{
+		"result": "OK",
+		"output": out.String(),
+		"GR":     m.GR,
+		"SP":     m.SP,
+		"PR":     m.PR,
+		"FR":     m.FR,
+		"steps":  m.Steps,
+	}
+	if err != nil {
+		result["result"] = "NG"
+		result["error"] = err.Error()
+	}
+	switch trace {
+	case "jsonl":
+		// 最終行は実行結果
+		json.NewEncoder(c.Writer).Encode(result)
+	case "csv":
+		// 最終行は実行結果
+		comet2.WriteCSVResult(m.Tracer, m, out.String(), err)
+	default:
+		c.JSON(200, result)
+	}
+}
+
+// language エラーメッセージの言語 (lang パラメータ、なければ Accept-Language)
+func language(c *gin.Context) string {
+	if lang := c.Query("lang"); lang != "" {
+		return parser.MatchLanguage(lang)
+	}
+	if lang := c.PostForm("lang"); lang != "" {
+		return parser.MatchLanguage(lang)
+	}
+	return parser.MatchLanguage(c.GetHeader("Accept-Language"))
+}
+
+// assemble ソースコードをアセンブルし、ラベル解決済みの機械語を返す
+// defines は EQU より優先する定数 (NAME=VALUE,...)
+func assemble(src, defines string) ([]opcode.Opcode, *parser.Parser, error) {
+	p := parser.New(lexer.New(src))
+	p.SetIncluder(library.Source)
+	p.DefineConstants(defines)
+	code, err := p.Assemble()
+	return code, p, err
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// post form を handler に POST する
+func post(handler gin.HandlerFunc, form url.Values) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/", handler)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+const echo = `MAIN	START
+	IN	BUF,LEN
+	OUT	BUF,LEN
+	RET
+BUF	DS	256
+LEN	DS	1
+	END
+`
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		form   url.Values
+		result string
+		output string
+		error  string
+	}{
+		{"echo", url.Values{"code": {echo}, "input": {"hello"}}, "OK", "hello\n", ""},
+		{"eof", url.Values{"code": {echo}}, "NG", "", "出力文字長 -1"},
+		{"assemble error", url.Values{"code": {"MAIN\tSTART\n\tLD\tGR9,BUF\n\tRET\nBUF\tDS\t1\n\tEND\n"}}, "NG", "", `"Code":"E0103"`},
+		{"lang", url.Values{"code": {"MAIN\tSTART\n\tLD\tGR9,BUF\n\tRET\nBUF\tDS\t1\n\tEND\n"}, "lang": {"en"}}, "NG", "", "is not a register"},
+		{"limit", url.Values{"code": {"MAIN\tSTART\nL\tJUMP\tL\n\tEND\n"}}, "NG", "", "上限"},
+		{"define", url.Values{"code": {"MAIN\tSTART\n\tIF\tDEBUG\n\tOUT\tMSG,LEN\n\tENDIF\n\tRET\nMSG\tDC\t'DEBUG'\nLEN\tDC\t5\n\tEND\n"}, "define": {"DEBUG=1"}}, "OK", "DEBUG\n", ""},
+		{"trace", url.Values{"code": {echo}, "trace": {"xml"}}, "NG", "", "trace"},
+	}
+	for _, tt := range tests {
+		w := post(run, tt.form)
+		var res map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Errorf("%s : %v %s", tt.name, err, w.Body.String())
+			continue
+		}
+		if res["result"] != tt.result {
+			t.Errorf("%s : result %v, want %s (%v)", tt.name, res["result"], tt.result, res["error"])
+		}
+		if tt.output != "" && res["output"] != tt.output {
+			t.Errorf("%s : output %q, want %q", tt.name, res["output"], tt.output)
+		}
+		if msg, _ := res["error"].(string); !strings.Contains(msg, tt.error) {
+			t.Errorf("%s : error %q, want %q", tt.name, msg, tt.error)
+		}
+	}
+}
+
+func TestRunTrace(t *testing.T) {
+	form := url.Values{"code": {echo}, "input": {"hi"}}
+	// START・IN (7命令)・OUT (7命令)・RET
+	const steps = 16
+
+	form.Set("trace", "jsonl")
+	w := post(run, form)
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("jsonl : Content-Type %s", ct)
+	}
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	if len(lines) != steps+1 {
+		t.Fatalf("jsonl : %d行\n%s", len(lines), w.Body.String())
+	}
+	var res map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[steps]), &res); err != nil || res["result"] != "OK" || res["output"] != "hi\n" {
+		t.Errorf("jsonl : 最終行 %s %v", lines[steps], err)
+	}
+
+	form.Set("trace", "csv")
+	w = post(run, form)
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("csv : Content-Type %s", ct)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil || len(records) != steps+2 {
+		t.Fatalf("csv : %d行 %v", len(records), err)
+	}
+	if last := records[steps+1]; last[2] != "OK" || last[9] != "hi\n" {
+		t.Errorf("csv : 最終行 %q", last)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		form   string
+		accept string
+		want   string
+	}{
+		{"default", "", "", "", "ja"},
+		{"header", "", "", "en-US,en;q=0.9", "en"},
+		{"form", "", "en", "ja", "en"},
+		{"query", "ja", "en", "en", "ja"},
+		{"unknown", "fr", "", "en", "ja"},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		var got string
+		router := gin.New()
+		router.POST("/", func(c *gin.Context) { got = language(c) })
+		req := httptest.NewRequest(http.MethodPost, "/?"+url.Values{"lang": {tt.query}}.Encode(), strings.NewReader(url.Values{"lang": {tt.form}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept-Language", tt.accept)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		if got != tt.want {
+			t.Errorf("%s : %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Package object 分割アセンブル用のオブジェクトファイルとリンカ
+//
+// オブジェクトファイル (.obj) は1つのソースをアセンブルした結果の JSON
+//
+//	{
+//	  "version": 1,
+//	  "name": "main.cas",
+//	  "code": [0, 4112, 0, 33024, ...],          // 0番地から配置した語列
+//	  "exports": [{"label": "MAIN", "addr": 0}], // START ラベル (他のモジュールから参照できる)
+//	  "imports": ["MULT"],                       // 他のモジュールの START ラベル
+//	  "relocations": [
+//	    {"addr": 2},                             // モジュール内のアドレス : 配置先の先頭番地を加算
+//	    {"addr": 4, "symbol": "MULT"}            // 外部シンボル : リンク時にそのアドレスを加算
+//	  ]
+//	}
+//
+// relocations はラベル・リテラルを参照する全ての語 (LabelToAddress で解決される AddrLabel) を含む
+// EQU の定数は番地ではないので含まない。式 (MULT+1 など) の語には定数部分を入れておき、番地を加算する
+// 実行イメージ (.img) はリンク済みの語列と実行開始番地
+//
+//	{"version": 1, "entry": 0, "code": [...], "symbols": [{"label": "MAIN", "addr": 0}, ...]}
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+)
+
+// Version オブジェクトファイル・実行イメージの形式
+const Version = 1
+
+// Object オブジェクトファイル
+type Object struct {
+	Version     int          `json:"version"`
+	Name        string       `json:"name,omitempty"`
+	Code        []uint16     `json:"code"`
+	Exports     []Symbol     `json:"exports"`
+	Imports     []string     `json:"imports,omitempty"`
+	Relocations []Relocation `json:"relocations,omitempty"`
+}
+
+// Symbol ラベルとアドレス
+type Symbol struct {
+	Label string `json:"label"`
+	Addr  uint16 `json:"addr"`
+}
+
+// Relocation 配置・リンク時に書き換える語
+type Relocation struct {
+	Addr   uint16 `json:"addr"`             //書き換える語の番地
+	Symbol string `json:"symbol,omitempty"` //外部シンボル (空の場合はモジュール内のアドレス)
+}
+
+// Assemble ソースをアセンブルしてオブジェクトにする
+// 解決できないラベルは他のモジュールの START ラベルとして imports に入れる
+// defines は parser.DefineConstants の形式の定数 (NAME=VALUE,...)、inc は INCLUDE のソース (nil なら INCLUDE できない)
+func Assemble(name, src, defines string, inc parser.Includer) (*Object, *parser.Parser, error) {
+	p := parser.New(lexer.New(src))
+	p.SetIncluder(inc)
+	if err := p.DefineConstants(defines); err != nil {
+		return nil, p, err
+	}
+	code, err := p.ParseProgram()
+	if err != nil {
+		return nil, p, err
+	}
+	if code, err = p.LiteralToMemory(code); err != nil {
+		return nil, p, err
+	}
+	o, err := New(name, code, p)
+	return o, p, err
+}
+
+// New ParseProgram・LiteralToMemory の結果からオブジェクトを作る
+// 式の値が決まらないなどのエラーは p.Errors に加える
+func New(name string, code []opcode.Opcode, p *parser.Parser) (*Object, error) {
+	o := &Object{Version: Version, Name: name, Exports: []Symbol{}}
+	symbols := p.SymbolTable()
+	imports := map[string]bool{}
+	unresolved := false
+	var addr uint16
+	for _, op := range code {
+		if op.AddrLabel != "" && (op.Length == 1 || op.Length == 2) {
+			// アドレスは命令の2語目 (DC ラベル は1語目)
+			r := Relocation{Addr: addr + uint16(op.Length) - 1}
+			value, base, err := resolve(op, symbols, func(label string) {
+				if !imports[label] {
+					imports[label] = true
+					o.Imports = append(o.Imports, label)
+				}
+			})
+			if err != nil {
+				p.ErrorAt(parser.CodeUndefined, op.AddrToken, err)
+				unresolved = true
+			}
+			op.Addr = value
+			switch base {
+			case "":
+				// EQU の定数・定数の式は再配置しない
+			case parser.Local:
+				o.Relocations = append(o.Relocations, r)
+			default:
+				r.Symbol = base
+				o.Relocations = append(o.Relocations, r)
+			}
+		}
+		words := op.Words()
+		o.Code = append(o.Code, words...)
+		addr += uint16(len(words))
+	}
+	if unresolved {
+		return nil, fmt.Errorf("Labelが解決できません")
+	}
+	for _, sy := range symbols.Entries() {
+		o.Exports = append(o.Exports, Symbol{Label: sy.Label, Addr: sy.Address})
+	}
+	return o, nil
+}
+
+// resolve op の AddrLabel (ラベル・リテラル・式) の値と番地の基準 (parser.Expr.Eval)
+// 解決できないラベルは外部シンボルとして extern に渡し、基準をその名前にする
+func resolve(op opcode.Opcode, symbols *symbol.SymbolTable, extern func(label string)) (uint16, string, error) {
+	e, err := parser.ParseExpr(op.AddrLabel)
+	if err != nil {
+		return 0, "", err
+	}
+	return e.Eval(func(label string) (uint16, string, error) {
+		sy, ok := symbols.ResolveIn(op.Scope, label)
+		switch {
+		case ok && sy.Kind == symbol.KindConstant:
+			return sy.Address, "", nil
+		case ok:
+			return sy.Address, parser.Local, nil
+		case strings.HasPrefix(label, "="):
+			return 0, "", parser.NewError(parser.CodeUndefined, label)
+		}
+		extern(label)
+		return 0, label, nil
+	})
+}
+
+// Read オブジェクトファイルを読み込む
+func Read(r io.Reader) (*Object, error) {
+	o := &Object{}
+	if err := json.NewDecoder(r).Decode(o); err != nil {
+		return nil, err
+	}
+	if o.Version != Version {
+		return nil, fmt.Errorf("オブジェクトファイルの形式 (version %d) に対応していません。", o.Version)
+	}
+	return o, nil
+}
+
+// Write オブジェクトファイルを書き出す
+func (o *Object) Write(w io.Writer) error {
+	return writeJSON(w, o)
+}
+
+// Image リンク済みの実行イメージ
+type Image struct {
+	Version int      `json:"version"`
+	Entry   uint16   `json:"entry"`
+	Code    []uint16 `json:"code"`
+	Symbols []Symbol `json:"symbols"`
+}
+
+// Link objs を順に配置し、アドレスを修正して1つの実行イメージにする
+// entry が空の場合は最初のオブジェクトの最初の START ラベル (START がなければ先頭) から実行する
+func Link(objs []*Object, entry string) (*Image, error) {
+	img := &Image{Version: Version, Symbols: []Symbol{}}
+	global := map[string]uint16{}
+	bases := make([]uint16, len(objs))
+	for i, o := range objs {
+		if len(img.Code)+len(o.Code) > 65536 {
+			return nil, fmt.Errorf("%s : プログラムが大きすぎます。", o.Name)
+		}
+		bases[i] = uint16(len(img.Code))
+		img.Code = append(img.Code, o.Code...)
+		for _, sy := range o.Exports {
+			if _, ok := global[sy.Label]; ok {
+				return nil, fmt.Errorf("%s : 重複定義エラー Label : %q", o.Name, sy.Label)
+			}
+			global[sy.Label] = bases[i] + sy.Addr
+			img.Symbols = append(img.Symbols, Symbol{Label: sy.Label, Addr: bases[i] + sy.Addr})
+		}
+	}
+	var errs []string
+	for i, o := range objs {
+		for _, r := range o.Relocations {
+			if int(r.Addr) >= len(o.Code) {
+				return nil, fmt.Errorf("%s : 再配置情報が不正です。addr : %d", o.Name, r.Addr)
+			}
+			offset := bases[i]
+			if r.Symbol != "" {
+				addr, ok := global[r.Symbol]
+				if !ok {
+					errs = append(errs, fmt.Sprintf("%s : %qは解決できません", o.Name, r.Symbol))
+					continue
+				}
+				offset = addr
+			}
+			img.Code[bases[i]+r.Addr] += offset
+		}
+	}
+	if len(errs) != 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	switch {
+	case entry != "":
+		addr, ok := global[entry]
+		if !ok {
+			return nil, fmt.Errorf("%qは解決できません", entry)
+		}
+		img.Entry = addr
+	case len(objs) != 0 && len(objs[0].Exports) != 0:
+		img.Entry = bases[0] + objs[0].Exports[0].Addr
+	case len(objs) != 0:
+		// START のないプログラムは先頭から実行する
+		img.Entry = bases[0]
+	default:
+		return nil, fmt.Errorf("%qがありません。", "START")
+	}
+	sort.SliceStable(img.Symbols, func(i, j int) bool { return img.Symbols[i].Addr < img.Symbols[j].Addr })
+	return img, nil
+}
+
+// ReadImage 実行イメージを読み込む
+func ReadImage(r io.Reader) (*Image, error) {
+	img := &Image{}
+	if err := json.NewDecoder(r).Decode(img); err != nil {
+		return nil, err
+	}
+	if img.Version != Version {
+		return nil, fmt.Errorf("実行イメージの形式 (version %d) に対応していません。", img.Version)
+	}
+	return img, nil
+}
+
+// Write 実行イメージを書き出す
+func (img *Image) Write(w io.Writer) error {
+	return writeJSON(w, img)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
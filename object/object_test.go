@@ -0,0 +1,155 @@
+package object
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DJSIer/OnlineGCASL2/comet2"
+)
+
+const mainSrc = `N	EQU	3
+MAIN	START
+	LD	GR1,=2
+	LAD	GR2,N
+	CALL	MULT
+	ST	GR0,ANS
+	LD	GR3,TABLE+1
+	RET
+ANS	DS	1
+TABLE	DC	5,MULT
+	END
+`
+
+// MULT GR0 ← GR1 × GR2
+const multSrc = `MULT	START
+	LAD	GR0,0
+LOOP	ADDA	GR0,GR1
+	SUBA	GR2,=1
+	JNZ	LOOP
+	RET
+	END
+`
+
+func assemble(t *testing.T, name, src string) *Object {
+	t.Helper()
+	o, p, err := Assemble(name, src, "", nil)
+	if err != nil {
+		t.Fatalf("%s : %v %v", name, err, p.Errors())
+	}
+	return o
+}
+
+func TestAssemble(t *testing.T) {
+	o := assemble(t, "main.cas", mainSrc)
+	// 0:MAIN 1:LD 3:LAD 5:CALL 7:ST 9:LD 11:RET 12:ANS 13:TABLE 15:=2 16:END
+	if want := []Symbol{{Label: "MAIN", Addr: 0}}; !reflect.DeepEqual(o.Exports, want) {
+		t.Errorf("exports %v", o.Exports)
+	}
+	if want := []string{"MULT"}; !reflect.DeepEqual(o.Imports, want) {
+		t.Errorf("imports %v", o.Imports)
+	}
+	// EQU の定数 (LAD GR2,N) は再配置しない
+	want := []Relocation{{Addr: 2}, {Addr: 6, Symbol: "MULT"}, {Addr: 8}, {Addr: 10}, {Addr: 14, Symbol: "MULT"}}
+	if !reflect.DeepEqual(o.Relocations, want) {
+		t.Errorf("relocations %v, want %v", o.Relocations, want)
+	}
+	if o.Code[2] != 15 || o.Code[4] != 3 || o.Code[6] != 0 || o.Code[10] != 14 {
+		t.Errorf("code %04X", o.Code)
+	}
+}
+
+func TestLink(t *testing.T) {
+	objs := []*Object{assemble(t, "main.cas", mainSrc), assemble(t, "mult.cas", multSrc)}
+	img, err := Link(objs, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := uint16(len(objs[0].Code))
+	if img.Entry != 0 || img.Code[6] != base || img.Code[14] != base || img.Code[base+5] != base+9 || img.Code[base+7] != base+3 {
+		t.Fatalf("entry %d code %04X", img.Entry, img.Code)
+	}
+	if want := []Symbol{{Label: "MAIN", Addr: 0}, {Label: "MULT", Addr: base}}; !reflect.DeepEqual(img.Symbols, want) {
+		t.Errorf("symbols %v", img.Symbols)
+	}
+	m := comet2.New()
+	m.LoadImage(img.Code, img.Entry)
+	if err := m.Run(1000); err != nil {
+		t.Fatal(err)
+	}
+	if m.Memory[12] != 6 || m.GR[3] != base {
+		t.Errorf("ANS %d GR3 #%04X", m.Memory[12], m.GR[3])
+	}
+
+	// 2番目のオブジェクトから実行する
+	if img, err = Link([]*Object{objs[1], objs[0]}, "MAIN"); err != nil || img.Entry != uint16(len(objs[1].Code)) {
+		t.Fatalf("entry %d %v", img.Entry, err)
+	}
+}
+
+func TestLinkError(t *testing.T) {
+	main := assemble(t, "main.cas", mainSrc)
+	mult := assemble(t, "mult.cas", multSrc)
+	broken := assemble(t, "broken.cas", multSrc)
+	broken.Relocations = append(broken.Relocations, Relocation{Addr: 100})
+	tests := []struct {
+		name  string
+		objs  []*Object
+		entry string
+		err   string
+	}{
+		{"import", []*Object{main}, "", `"MULT"は解決できません`},
+		{"duplicate", []*Object{main, mult, mult}, "", "重複定義エラー"},
+		{"relocation", []*Object{broken}, "", "再配置情報が不正です"},
+		{"entry", []*Object{main, mult}, "SUB", `"SUB"は解決できません`},
+		{"empty", nil, "", "START"},
+	}
+	for _, tt := range tests {
+		if _, err := Link(tt.objs, tt.entry); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s : %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestAssembleError(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		code string
+	}{
+		{"syntax", "MAIN\tSTART\n\tLD\tGR9,X\n\tEND\n", "E0103"},
+		// 外部シンボルも番地なので掛け算に使えない
+		{"expression", "MAIN\tSTART\n\tLD\tGR1,X*2\n\tRET\n\tEND\n", "E0502"},
+	}
+	for _, tt := range tests {
+		_, p, err := Assemble("err.cas", tt.src, "", nil)
+		if err == nil || len(p.Errors()) == 0 || p.Errors()[0].Code != tt.code {
+			t.Errorf("%s : %v %v", tt.name, err, p.Errors())
+		}
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	o := assemble(t, "main.cas", mainSrc)
+	var buf bytes.Buffer
+	if err := o.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(&buf)
+	if err != nil || !reflect.DeepEqual(read, o) {
+		t.Fatalf("%+v %v", read, err)
+	}
+	img, _ := Link([]*Object{o, assemble(t, "mult.cas", multSrc)}, "")
+	buf.Reset()
+	img.Write(&buf)
+	if read, err := ReadImage(&buf); err != nil || !reflect.DeepEqual(read, img) {
+		t.Fatalf("%+v %v", read, err)
+	}
+	if _, err := Read(strings.NewReader(`{"version":2,"code":[]}`)); err == nil {
+		t.Fatal("version 2 を読み込めます")
+	}
+	if _, err := ReadImage(strings.NewReader(`{"version":0}`)); err == nil {
+		t.Fatal("version 0 を読み込めます")
+	}
+}
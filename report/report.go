@@ -0,0 +1,72 @@
+// Package report コマンドラインツールのエラー・警告の出力
+//
+//	main.cas:12:5: error[E0103]: LD "GR9" "GR9"はレジスタではありません。
+//	lib.cas:3:9: warning[W0001]: "GR0"が使用されています
+//		main.cas:2:10 から INCLUDE
+//
+// INCLUDE したファイルの中なら、そのファイルの位置に続けて INCLUDE の位置を内側から出す
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+)
+
+// Reporter ソース Name のエラー・警告を Lang の言語で W に書き出す
+type Reporter struct {
+	W    io.Writer
+	Name string
+	Lang string
+}
+
+// New name のエラー・警告を lang (parser.Languages) で w に書き出す
+func New(w io.Writer, name, lang string) *Reporter {
+	return &Reporter{W: w, Name: name, Lang: lang}
+}
+
+// Errors errs を error として出力する
+func (r *Reporter) Errors(errs []parser.ParserError) {
+	for _, e := range parser.LocalizeErrors(errs, r.Lang) {
+		r.Report(e.Line, e.Column, e.Include, "error", e.Code, e.Message)
+	}
+}
+
+// Warnings ws を warning として出力する
+func (r *Reporter) Warnings(ws []parser.ParserWarning) {
+	for _, w := range parser.LocalizeWarnings(ws, r.Lang) {
+		r.Report(w.Line, w.Column, w.Include, "warning", w.Code, w.Message)
+	}
+}
+
+// Report name:line:column: kind[code]: msg (column・code がなければ省く)
+// stack は INCLUDE したファイルの位置 (内側から、ParserError.Include)
+func (r *Reporter) Report(line, column int, stack []string, kind, code, msg string) {
+	at := fmt.Sprintf("%s:%d", r.Name, line)
+	if column > 0 {
+		at += fmt.Sprintf(":%d", column)
+	}
+	if code != "" {
+		kind += "[" + code + "]"
+	}
+	if len(stack) == 0 {
+		fmt.Fprintf(r.W, "%s: %s: %s\n", at, kind, message(msg))
+		return
+	}
+	from := "\t%s から INCLUDE\n"
+	if r.Lang == "en" {
+		from = "\tincluded from %s\n"
+	}
+	fmt.Fprintf(r.W, "%s: %s: %s\n", stack[0], kind, message(msg))
+	for _, s := range stack[1:] {
+		fmt.Fprintf(r.W, from, s)
+	}
+	fmt.Fprintf(r.W, from, at)
+}
+
+// message 複数行のメッセージを1行にする
+func message(msg string) string {
+	return strings.Join(strings.Fields(strings.Replace(msg, "\n", " ", -1)), " ")
+}
@@ -0,0 +1,57 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+)
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		lang   string
+		line   int
+		column int
+		stack  []string
+		kind   string
+		code   string
+		msg    string
+		want   string
+	}{
+		{"error", "ja", 12, 5, nil, "error", "E0103", "\"GR9\"はレジスタではありません。", "main.cas:12:5: error[E0103]: \"GR9\"はレジスタではありません。\n"},
+		{"no column", "ja", 3, 0, nil, "warning", "", "a\n  b", "main.cas:3: warning: a b\n"},
+		{"include", "ja", 2, 10, []string{"lib.cas:3:9", "io.cas:1:2"}, "warning", "W0001", "w", "lib.cas:3:9: warning[W0001]: w\n\tio.cas:1:2 から INCLUDE\n\tmain.cas:2:10 から INCLUDE\n"},
+		{"include en", "en", 2, 10, []string{"lib.cas:3:9"}, "error", "E0103", "e", "lib.cas:3:9: error[E0103]: e\n\tincluded from main.cas:2:10\n"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		New(&b, "main.cas", tt.lang).Report(tt.line, tt.column, tt.stack, tt.kind, tt.code, tt.msg)
+		if b.String() != tt.want {
+			t.Errorf("%s : %q, want %q", tt.name, b.String(), tt.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	errs := []parser.ParserError{{Line: 2, Code: parser.CodeRegister, Args: []string{"GR9", "LD"}, Column: 5, EndColumn: 8}}
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"ja", "a.cas:2:5: error[E0103]: LD \"GR9\" \"GR9\"はレジスタではありません。\n"},
+		{"en", "a.cas:2:5: error[E0103]: LD \"GR9\" \"GR9\" is not a register.\n"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		New(&b, "a.cas", tt.lang).Errors(errs)
+		if b.String() != tt.want {
+			t.Errorf("%s : %q, want %q", tt.lang, b.String(), tt.want)
+		}
+	}
+	var b bytes.Buffer
+	New(&b, "a.cas", "ja").Warnings([]parser.ParserWarning{{Line: 4, Code: parser.CodeUnusedLabel, Args: []string{"Y"}, Column: 1}})
+	if want := "a.cas:4:1: warning[W0002]: \"Y\"は使用されていません\n"; b.String() != want {
+		t.Errorf("%q, want %q", b.String(), want)
+	}
+}
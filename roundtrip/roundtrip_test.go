@@ -0,0 +1,239 @@
+// Package roundtrip 逆アセンブラのテスト
+// 生成した CASL2 の命令を assemble → disassemble → assemble し、語列が一致するか確認する
+package roundtrip
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/DJSIer/OnlineGCASL2/disasm"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+)
+
+// labels 生成した命令から参照できるラベル (wrap で定義する)
+var labels = []string{"PROG", "DATA", "TABLE"}
+
+// wrap 命令1つを START・END で囲んだプログラムにする (命令は1番地から配置される)
+func wrap(inst string) string {
+	return "PROG\tSTART\n" + inst + "\n\tRET\nDATA\tDS\t2\nTABLE\tDC\t1,2,3\n\tEND\n"
+}
+
+// generate opcode.Instructions の形式に従った CASL2 の命令を1つ生成する
+func generate(r *rand.Rand) string {
+	var inst opcode.Instruction
+	for {
+		// NOP は parser が受け付けない
+		if inst = opcode.Instructions[r.Intn(len(opcode.Instructions))]; inst.Mnemonic != "NOP" {
+			break
+		}
+	}
+	switch inst.Format {
+	case opcode.FormatR:
+		return fmt.Sprintf("\t%s\t%s", inst.Mnemonic, register(r, 0))
+	case opcode.FormatR1R2:
+		return fmt.Sprintf("\t%s\t%s,%s", inst.Mnemonic, register(r, 0), register(r, 0))
+	case opcode.FormatRAdrX:
+		return fmt.Sprintf("\t%s\t%s,%s", inst.Mnemonic, register(r, 0), address(r, inst))
+	case opcode.FormatAdrX:
+		return fmt.Sprintf("\t%s\t%s", inst.Mnemonic, address(r, inst))
+	}
+	return "\t" + inst.Mnemonic
+}
+
+// generateInvalid 指標レジスタの位置にレジスタ以外を置いた命令を生成する
+// カンマの後のレジスタ確認が抜けている *Statment を見つけるため
+func generateInvalid(r *rand.Rand) string {
+	var inst opcode.Instruction
+	for {
+		if inst = opcode.Instructions[r.Intn(len(opcode.Instructions))]; inst.Length() == 2 {
+			break
+		}
+	}
+	adr := address(r, inst)
+	if i := strings.Index(adr, ","); i >= 0 {
+		adr = adr[:i]
+	}
+	adr += "," + invalidIndex[r.Intn(len(invalidIndex))]
+	if inst.Format == opcode.FormatRAdrX {
+		return fmt.Sprintf("\t%s\t%s,%s", inst.Mnemonic, register(r, 0), adr)
+	}
+	return fmt.Sprintf("\t%s\t%s", inst.Mnemonic, adr)
+}
+
+// invalidIndex 指標レジスタとして不正なオペランド
+var invalidIndex = []string{"5", "#0001", "DATA", "=1", "GR8"}
+
+func register(r *rand.Rand, min int) string {
+	return fmt.Sprintf("GR%d", min+r.Intn(8-min))
+}
+
+// address adr [,x]  シフト数・SVC 番号にはリテラルを使わない
+func address(r *rand.Rand, inst opcode.Instruction) string {
+	var adr string
+	kinds := 7
+	if isCount(inst) {
+		kinds = 4
+	}
+	switch r.Intn(kinds) {
+	case 0:
+		adr = fmt.Sprint(r.Intn(32768))
+	case 1:
+		adr = fmt.Sprint(-1 - r.Intn(32768))
+	case 2:
+		adr = fmt.Sprintf("#%04X", r.Intn(65536))
+	case 3:
+		adr = labels[r.Intn(len(labels))]
+	case 4:
+		adr = fmt.Sprintf("=%d", r.Intn(65536))
+	case 5:
+		adr = fmt.Sprintf("=#%04X", r.Intn(65536))
+	case 6:
+		adr = "='" + text(r) + "'"
+	}
+	if r.Intn(2) == 0 {
+		adr += "," + register(r, 1)
+	}
+	return adr
+}
+
+// text 文字定数 (' は2つ重ねて書く)
+func text(r *rand.Rand) string {
+	const letters = "ABCXYZ019 '"
+	var b strings.Builder
+	for n := 1 + r.Intn(4); n > 0; n-- {
+		ch := letters[r.Intn(len(letters))]
+		if ch == '\'' {
+			b.WriteByte(ch)
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+// isCount adr が番地ではなく数値として扱われる命令 (シフト・SVC)
+func isCount(inst opcode.Instruction) bool {
+	return inst.Code >= 0x50 && inst.Code <= 0x53 || inst.Code == 0xF0
+}
+
+// assemble wrap したソースをアセンブルし、語列を返す
+func assemble(src string) ([]uint16, *symbol.SymbolTable, error) {
+	p := parser.New(lexer.New(src))
+	code, err := p.Assemble()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v %v", err, p.Errors())
+	}
+	var words []uint16
+	for _, op := range code {
+		words = append(words, op.Words()...)
+	}
+	return words, p.SymbolTable(), nil
+}
+
+// check 命令を assemble → disassemble → assemble し、語列が一致するか確認する
+func check(inst string) error {
+	first, symbols, err := assemble(wrap(inst))
+	if err != nil {
+		return err
+	}
+	line := disasm.New(symbols).Decode(first[1:], 1)
+	second, _, err := assemble(wrap(line.String()))
+	if err != nil {
+		return fmt.Errorf("%q : %v", line.String(), err)
+	}
+	if len(first) != len(second) {
+		return fmt.Errorf("%q : 語数が一致しません %d : %d", line.String(), len(first), len(second))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return fmt.Errorf("%q : %d番地 #%04X : #%04X", line.String(), i, first[i], second[i])
+		}
+	}
+	return nil
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []string{
+		"\tLD\tGR1,GR2",
+		"\tLD\tGR0,DATA,GR7",
+		"\tST\tGR3,TABLE",
+		"\tLAD\tGR1,-1",
+		"\tADDA\tGR1,=10",
+		"\tSUBL\tGR2,=#FFFF",
+		"\tAND\tGR4,='A''B'",
+		"\tCPL\tGR5,GR6",
+		"\tSLA\tGR1,3",
+		"\tSRL\tGR1,15,GR2",
+		"\tJUMP\tPROG",
+		"\tJPL\t#8000",
+		"\tPUSH\t0,GR1",
+		"\tPOP\tGR7",
+		"\tCALL\tDATA",
+		"\tRET",
+		"\tSVC\t#0001",
+	}
+	for _, inst := range tests {
+		if err := check(inst); err != nil {
+			t.Errorf("%q : %v", inst, err)
+		}
+	}
+}
+
+// TestRoundTripRandom 乱数の種ごとに生成した命令で確認する
+func TestRoundTripRandom(t *testing.T) {
+	n := 2000
+	if testing.Short() {
+		n = 200
+	}
+	for seed := int64(1); seed <= 5; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		for i := 0; i < n; i++ {
+			inst := generate(r)
+			if err := check(inst); err != nil {
+				t.Errorf("seed %d : %q : %v", seed, inst, err)
+			}
+		}
+	}
+}
+
+// TestInvalidIndex 不正な指標レジスタはアセンブルエラーになる
+func TestInvalidIndex(t *testing.T) {
+	tests := []string{
+		"\tLD\tGR1,DATA,5",
+		"\tST\tGR1,DATA,#0001",
+		"\tLAD\tGR1,DATA,DATA",
+		"\tJUMP\tPROG,=1",
+		"\tSVC\t1,GR8",
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		tests = append(tests, generateInvalid(r))
+	}
+	for _, inst := range tests {
+		if words, _, err := assemble(wrap(inst)); err == nil {
+			t.Errorf("%q : エラーになりません %04X", inst, words[1:])
+		}
+	}
+}
+
+// FuzzRoundTrip 乱数の種から命令を生成して確認する (go test -fuzz FuzzRoundTrip)
+func FuzzRoundTrip(f *testing.F) {
+	for _, seed := range []int64{0, 1, 42, 1 << 40} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, seed int64) {
+		r := rand.New(rand.NewSource(seed))
+		inst := generate(r)
+		if err := check(inst); err != nil {
+			t.Errorf("%q : %v", inst, err)
+		}
+		invalid := generateInvalid(r)
+		if _, _, err := assemble(wrap(invalid)); err == nil {
+			t.Errorf("%q : エラーになりません", invalid)
+		}
+	})
+}
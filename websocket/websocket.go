@@ -0,0 +1,208 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// acceptGUID Sec-WebSocket-Accept 計算用の GUID (RFC 6455)
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// MaxMessageSize 受信するメッセージの最大バイト数
+const MaxMessageSize = 1 << 20
+
+// frame opcode
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Conn WebSocket connection (server side)
+type Conn struct {
+	conn    net.Conn
+	rw      *bufio.ReadWriter
+	mu      sync.Mutex
+	closing bool //close フレームを送信済み (close ハンドシェイクは1回だけ)
+}
+
+// Upgrade HTTP リクエストを WebSocket 接続に切り替える
+// Origin があれば Host と同じでなければならない (他のサイトのページからの接続を拒否する)
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, fmt.Errorf("websocket: method %s is not allowed", r.Method)
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, fmt.Errorf("websocket: not a websocket handshake")
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		return nil, fmt.Errorf("websocket: unsupported version %q", r.Header.Get("Sec-Websocket-Version"))
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || !strings.EqualFold(u.Host, r.Host) {
+			return nil, fmt.Errorf("websocket: origin %q is not allowed", origin)
+		}
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if key == "" {
+		return nil, fmt.Errorf("websocket: Sec-WebSocket-Key is missing")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("websocket: response does not implement http.Hijacker")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	h := sha1.Sum([]byte(key + acceptGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, rw: rw}, nil
+}
+
+// ReadMessage テキスト・バイナリメッセージを1つ読み込む
+// ping には pong を返し、close を受信した場合は io.EOF を返す
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opClose:
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			c.writeFrame(opClose, payload)
+			return nil, io.EOF
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opText, opBinary, opContinuation:
+			if len(msg)+len(payload) > MaxMessageSize {
+				return nil, fmt.Errorf("websocket: message too large")
+			}
+			msg = append(msg, payload...)
+			if fin {
+				return msg, nil
+			}
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %d", op)
+		}
+	}
+}
+
+// WriteMessage テキストメッセージを送信する
+func (c *Conn) WriteMessage(msg []byte) error {
+	return c.writeFrame(opText, msg)
+}
+
+// Close close フレームを送信して接続を閉じる
+// close ハンドシェイクが済んでいれば close フレームは送らない
+func (c *Conn) Close() error {
+	c.writeFrame(opClose, []byte{0x03, 0xE8}) //1000 normal closure
+	return c.conn.Close()
+}
+
+func (c *Conn) readFrame() (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.rw, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := head[0]&0x80 != 0
+	op := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if !masked {
+		return false, 0, nil, fmt.Errorf("websocket: client frame is not masked")
+	}
+	if length > MaxMessageSize {
+		return false, 0, nil, fmt.Errorf("websocket: message too large")
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		if op == opClose {
+			return nil
+		}
+		return fmt.Errorf("websocket: connection is closing")
+	}
+	c.closing = op == opClose
+	head := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n < 126:
+		head = append(head, byte(n))
+	case n <= 0xFFFF:
+		head = append(head, 126, byte(n>>8), byte(n))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		head = append(append(head, 127), ext[:]...)
+	}
+	c.rw.Write(head)
+	c.rw.Write(payload)
+	return c.rw.Flush()
+}
+
+func headerContains(h http.Header, name string, value string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
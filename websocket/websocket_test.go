@@ -0,0 +1,190 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echoServer 受信したメッセージに "echo:" を付けて返すサーバ
+func echoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer c.Close()
+		for {
+			m, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			c.WriteMessage(append([]byte("echo:"), m...))
+		}
+	}))
+}
+
+// dial header でハンドシェイクし、接続と応答を返す
+func dial(t *testing.T, srv *httptest.Server, header string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: x\r\n"+header+"\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, br, resp
+}
+
+const handshake = "Upgrade: websocket\r\nConnection: keep-alive, Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+
+func TestUpgrade(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"ok", handshake, http.StatusSwitchingProtocols},
+		{"no upgrade", "Connection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n", http.StatusBadRequest},
+		{"version", "Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 8\r\n", http.StatusBadRequest},
+		{"no key", "Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Version: 13\r\n", http.StatusBadRequest},
+		{"origin", handshake + "Origin: http://X\r\n", http.StatusSwitchingProtocols},
+		{"other origin", handshake + "Origin: http://evil.example\r\n", http.StatusBadRequest},
+		{"bad origin", handshake + "Origin: ://x\r\n", http.StatusBadRequest},
+	}
+	srv := echoServer()
+	defer srv.Close()
+	for _, tt := range tests {
+		conn, _, resp := dial(t, srv, tt.header)
+		conn.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s : status %d, want %d", tt.name, resp.StatusCode, tt.status)
+		}
+	}
+	conn, _, resp := dial(t, srv, handshake)
+	defer conn.Close()
+	// RFC 6455 1.3 の例
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept %q", got)
+	}
+}
+
+// frame マスクしたクライアントフレーム
+func frame(op byte, fin bool, payload []byte) []byte {
+	head := []byte{op}
+	if fin {
+		head[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		head = append(head, 0x80|byte(n))
+	case n <= 0xFFFF:
+		head = append(head, 0x80|126, byte(n>>8), byte(n))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		head = append(append(head, 0x80|127), ext[:]...)
+	}
+	mask := []byte{1, 2, 3, 4}
+	head = append(head, mask...)
+	for i, b := range payload {
+		head = append(head, b^mask[i%4])
+	}
+	return head
+}
+
+// readFrame サーバのフレームを1つ読み込む
+func readFrame(t *testing.T, br *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(br, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(br, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return head[0], payload
+}
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames [][]byte
+		want   string
+	}{
+		{"short", [][]byte{frame(opText, true, []byte("hello"))}, "hello"},
+		{"126", [][]byte{frame(opText, true, []byte(strings.Repeat("x", 300)))}, strings.Repeat("x", 300)},
+		{"127", [][]byte{frame(opBinary, true, []byte(strings.Repeat("y", 70000)))}, strings.Repeat("y", 70000)},
+		{"fragment", [][]byte{frame(opText, false, []byte("ab")), frame(opContinuation, false, []byte("cd")), frame(opContinuation, true, []byte("ef"))}, "abcdef"},
+		{"pong", [][]byte{frame(opPong, true, nil), frame(opText, true, []byte("x"))}, "x"},
+	}
+	srv := echoServer()
+	defer srv.Close()
+	for _, tt := range tests {
+		conn, br, _ := dial(t, srv, handshake)
+		for _, f := range tt.frames {
+			conn.Write(f)
+		}
+		op, payload := readFrame(t, br)
+		if op != 0x80|opText || string(payload) != "echo:"+tt.want {
+			t.Errorf("%s : op #%02X payload %d bytes", tt.name, op, len(payload))
+		}
+		conn.Close()
+	}
+}
+
+func TestControl(t *testing.T) {
+	srv := echoServer()
+	defer srv.Close()
+	conn, br, _ := dial(t, srv, handshake)
+	defer conn.Close()
+	conn.Write(frame(opPing, true, []byte("p")))
+	if op, payload := readFrame(t, br); op != 0x80|opPong || string(payload) != "p" {
+		t.Fatalf("ping : op #%02X payload %q", op, payload)
+	}
+	conn.Write(frame(opClose, true, []byte{0x03, 0xE8, 'b', 'y', 'e'}))
+	if op, payload := readFrame(t, br); op != 0x80|opClose || string(payload) != "\x03\xE8" {
+		t.Fatalf("close : op #%02X payload %q", op, payload)
+	}
+	// Close は2つ目の close フレームを送らずに切断する
+	if b, err := br.ReadByte(); err != io.EOF {
+		t.Fatalf("#%02X %v", b, err)
+	}
+}
+
+func TestUnmasked(t *testing.T) {
+	srv := echoServer()
+	defer srv.Close()
+	conn, br, _ := dial(t, srv, handshake)
+	defer conn.Close()
+	conn.Write([]byte{0x80 | opText, 1, 'x'})
+	// ReadMessage がエラーになり、サーバは close を送って切断する
+	if op, _ := readFrame(t, br); op != 0x80|opClose {
+		t.Fatalf("op #%02X", op)
+	}
+	if _, err := br.ReadByte(); err != io.EOF {
+		t.Fatalf("切断されていません %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package xref
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/opcode"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/symbol"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/token"
+)
+
+// Entry ラベル1つの定義と参照
+type Entry struct {
+	Label      string `json:"label"`
+	Program    string `json:"program,omitempty"` //定義されたプログラムの START ラベル
+	Line       int    `json:"line"`              //定義された行
+	Addr       uint16 `json:"addr"`
+	References []int  `json:"references"`         //参照している行 (昇順)
+	Entry      bool   `json:"entry,omitempty"`    //START ラベル (他のプログラムから参照できる)
+	Constant   bool   `json:"constant,omitempty"` //EQU の定数 (Addr は値)
+}
+
+// New LabelToAddress 済みの code の AddrLabel (式の中のラベルを含む) から相互参照表を作る (リテラルは含まない)
+// START のオペランドは START の行からの参照とする
+func New(code []opcode.Opcode, symbols *symbol.SymbolTable) []Entry {
+	programs := map[int]symbol.Symbol{}
+	for _, op := range code {
+		if op.Token.Type == token.START && op.Label != nil {
+			programs[op.Scope] = *op.Label
+		}
+	}
+	entries := map[int]*Entry{}
+	var list []*Entry
+	for _, sy := range symbols.Symbols() {
+		if strings.HasPrefix(sy.Label, "=") {
+			continue
+		}
+		e := &Entry{Label: sy.Label, Line: sy.Line, Addr: sy.Address, References: []int{}, Constant: sy.Kind == symbol.KindConstant}
+		if start, ok := programs[sy.Scope]; ok && !e.Constant {
+			e.Program = start.Label
+			if start.Label == sy.Label {
+				e.Entry = true
+			} else if start.Address == sy.Address {
+				// START のオペランド (実行開始番地)
+				e.References = append(e.References, start.Line)
+			}
+		}
+		entries[sy.Index] = e
+		list = append(list, e)
+	}
+	for _, op := range code {
+		if op.AddrLabel == "" {
+			continue
+		}
+		for _, label := range labels(op.AddrLabel) {
+			sy, ok := symbols.ResolveIn(op.Scope, label)
+			if !ok {
+				continue
+			}
+			if e, ok := entries[sy.Index]; ok {
+				e.References = append(e.References, op.Token.Line)
+			}
+		}
+	}
+	result := make([]Entry, len(list))
+	for i, e := range list {
+		sort.Ints(e.References)
+		e.References = unique(e.References)
+		result[i] = *e
+	}
+	return result
+}
+
+// labels AddrLabel (ラベル・リテラル・式) が参照するラベル
+func labels(addrLabel string) []string {
+	e, err := parser.ParseExpr(addrLabel)
+	if err != nil {
+		return nil
+	}
+	return e.Labels()
+}
+
+func unique(lines []int) []int {
+	out := lines[:0]
+	for i, l := range lines {
+		if i == 0 || l != lines[i-1] {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Warnings 参照されていないラベル (START ラベル・EQU の定数・マクロのローカルラベル NAME.n を除く)
+// ラベルは行の先頭に書くので、位置は定義された行の1文字目から
+// 定数は IF からも参照されるため対象にしない
+func Warnings(entries []Entry) []parser.ParserWarning {
+	var warnings []parser.ParserWarning
+	unused, _ := parser.Lookup(parser.CodeUnusedLabel, parser.DefaultLanguage)
+	for _, e := range entries {
+		if !e.Entry && !e.Constant && len(e.References) == 0 && !strings.Contains(e.Label, ".") {
+			args := []string{e.Label}
+			warnings = append(warnings, parser.ParserWarning{Line: e.Line, Code: parser.CodeUnusedLabel, Args: args, Message: unused.Format(args), Column: 1, EndColumn: 1 + len(e.Label)})
+		}
+	}
+	return warnings
+}
+
+// Text ラベル・番地 (定数は値)・定義行・参照行の表形式
+func Text(entries []Entry) string {
+	width := len("LABEL")
+	for _, e := range entries {
+		if len(e.Label) > width {
+			width = len(e.Label)
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-*s ADDR LINE REFERENCES\n", width, "LABEL")
+	for _, e := range entries {
+		refs := make([]string, len(e.References))
+		for i, l := range e.References {
+			refs[i] = fmt.Sprint(l)
+		}
+		s := fmt.Sprintf("%-*s %04X %4d %s", width, e.Label, e.Addr, e.Line, strings.Join(refs, ","))
+		b.WriteString(strings.TrimRight(s, " "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package xref
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/lexer"
+	"github.com/DJSIer/OnlineGCASL2/gcasl2/parser"
+)
+
+const src = `N	EQU	2
+MAIN	START	BEGIN
+X	DS	N
+BEGIN	LD	GR1,X+1
+	CALL	SUB
+	ST	GR1,X
+UNUSED	RET
+	END
+SUB	START
+X	DC	1
+	LD	GR2,X
+	RET
+	END
+`
+
+func entries(t *testing.T, src string) []Entry {
+	t.Helper()
+	p := parser.New(lexer.New(src))
+	code, err := p.Assemble()
+	if err != nil {
+		t.Fatal(err, p.Errors())
+	}
+	return New(code, p.SymbolTable())
+}
+
+func TestNew(t *testing.T) {
+	// START のオペランド (BEGIN) は START の行からの参照、式 (X+1) の中のラベルも参照
+	want := []Entry{
+		{Label: "N", Line: 1, Addr: 2, References: []int{}, Constant: true},
+		{Label: "MAIN", Program: "MAIN", Line: 2, Addr: 3, References: []int{}, Entry: true},
+		{Label: "X", Program: "MAIN", Line: 3, Addr: 1, References: []int{4, 6}},
+		{Label: "BEGIN", Program: "MAIN", Line: 4, Addr: 3, References: []int{2}},
+		{Label: "UNUSED", Program: "MAIN", Line: 7, Addr: 9, References: []int{}},
+		{Label: "SUB", Program: "SUB", Line: 9, Addr: 11, References: []int{5}, Entry: true},
+		{Label: "X", Program: "SUB", Line: 10, Addr: 12, References: []int{11}},
+	}
+	if got := entries(t, src); !reflect.DeepEqual(got, want) {
+		t.Errorf("%+v\nwant %+v", got, want)
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	// マクロのローカルラベル (L.1) は警告しない
+	src := "WAIT\tMACRO\nL\tLAD\tGR0,0\n\tMEND\nMAIN\tSTART\n\tWAIT\nUNUSED\tRET\n\tEND\n"
+	ws := Warnings(entries(t, src))
+	if len(ws) != 1 || ws[0].Line != 6 || ws[0].Code != parser.CodeUnusedLabel || ws[0].Column != 1 || ws[0].EndColumn != 7 {
+		t.Fatalf("%+v", ws)
+	}
+	if ws[0].Message != `"UNUSED"は使用されていません` {
+		t.Errorf("%q", ws[0].Message)
+	}
+}
+
+func TestText(t *testing.T) {
+	want := `LABEL  ADDR LINE REFERENCES
+N      0002    1
+MAIN   0003    2
+X      0001    3 4,6
+BEGIN  0003    4 2
+UNUSED 0009    7
+SUB    000B    9 5
+X      000C   10 11
+`
+	if got := Text(entries(t, src)); got != want {
+		t.Errorf("\n%s\nwant\n%s", got, want)
+	}
+}